@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/priince9381/irm_backend/internal/config"
 	"github.com/priince9381/irm_backend/internal/handlers"
+	"github.com/priince9381/irm_backend/internal/jobs"
 	"github.com/priince9381/irm_backend/internal/middleware"
+	"github.com/priince9381/irm_backend/internal/publisher"
 	"github.com/priince9381/irm_backend/internal/repository"
-	"github.com/priince9381/irm_backend/internal/utils"
+	"github.com/priince9381/irm_backend/internal/storage"
 )
 
 func main() {
@@ -25,11 +29,29 @@ func main() {
 		log.Fatalf("Failed to connect to Elasticsearch: %v", err)
 	}
 
-	// Ensure upload directory exists
-	uploadDir := "/home/administrator/test/irm_backend/uploads"
-	if err := utils.EnsureDir(uploadDir); err != nil {
-		log.Fatalf("Failed to create upload directory: %v", err)
+	// Initialize media storage
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	staging, err := storage.NewStaging(cfg.UploadStagingDir)
+	if err != nil {
+		log.Fatalf("Failed to create upload staging directory: %v", err)
+	}
+
+	// Start background jobs
+	go jobs.NewTrashPurger(esDB, store, cfg.TrashRetention).Run(context.Background(), time.Hour)
+	go jobs.NewUploadReaper(esDB, store, staging).Run(context.Background(), time.Hour)
+	go jobs.NewMediaProcessor(esDB, store).Run(context.Background(), 30*time.Second)
+	go jobs.NewRecurrenceMaterializer(esDB, cfg.RecurrenceHorizon).Run(context.Background(), 15*time.Minute)
+
+	// Platform clients are registered here. Due posts to platforms without a
+	// client stay scheduled; the scheduler still recovers interrupted posts.
+	publishers := publisher.NewRegistry()
+	if publishers.Len() == 0 {
+		log.Println("No platform publishers configured, scheduled posts will not be published")
 	}
+	go jobs.NewScheduler(esDB, publishers).Run(context.Background(), time.Minute)
 
 	// Initialize router
 	router := gin.Default()
@@ -37,8 +59,9 @@ func main() {
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Length, Upload-Offset, Upload-Expires, Upload-Media-Id")
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
 			return
@@ -46,12 +69,15 @@ func main() {
 		c.Next()
 	})
 
-	// Serve static files
-	router.Static("/media/uploads", uploadDir)
+	// Serve uploaded files and accept signed direct uploads when they are stored on local disk
+	if local, ok := store.(*storage.Local); ok {
+		router.Static(local.ServePath(), local.Dir())
+		local.SignUploads([]byte(cfg.JWTSecret), "/api/v1/media/direct")
+	}
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(esDB, cfg.JWTSecret)
-	postHandler := handlers.NewHandler(esDB, cfg)
+	postHandler := handlers.NewHandler(esDB, cfg, store, staging)
 
 	// Public routes
 	public := router.Group("/api/v1/auth")
@@ -60,14 +86,90 @@ func main() {
 		public.POST("/login", authHandler.Login)
 	}
 
+	// Direct uploads to local storage are authenticated by their signed URL
+	router.PUT("/api/v1/media/direct/*key", postHandler.ReceiveDirectUpload)
+
 	// Protected routes
 	protected := router.Group("/api/v1")
 	protected.Use(middleware.JWTAuth(cfg.JWTSecret))
 	{
+		protected.GET("/users/me/preferences", postHandler.GetPreferences)
+		protected.PUT("/users/me/preferences", postHandler.UpdatePreferences)
+		protected.GET("/users/me/storage", postHandler.GetStorageUsage)
+
 		protected.POST("/posts", postHandler.CreatePost)
 		protected.GET("/posts", postHandler.GetPosts)
+		protected.GET("/posts/trash", postHandler.GetTrash)
+		protected.POST("/posts/import", postHandler.ImportPosts)
+		protected.GET("/posts/export", postHandler.ExportPosts)
+		protected.POST("/posts/bulk", postHandler.BulkUpdatePosts)
+		protected.GET("/posts/:id", postHandler.GetPost)
 		protected.PUT("/posts/:id", postHandler.UpdatePost)
+		protected.PATCH("/posts/:id/schedule", postHandler.ReschedulePost)
 		protected.DELETE("/posts/:id", postHandler.DeletePost)
+		protected.POST("/posts/:id/restore", postHandler.RestorePost)
+		protected.POST("/posts/:id/duplicate", postHandler.DuplicatePost)
+		protected.GET("/posts/:id/copies", postHandler.GetPostCopies)
+		protected.PUT("/posts/:id/recurrence", postHandler.SetRecurrence)
+		protected.DELETE("/posts/:id/recurrence", postHandler.EndRecurrence)
+		protected.GET("/posts/:id/occurrences", postHandler.GetOccurrences)
+		protected.POST("/posts/:id/occurrences/skip", postHandler.SkipOccurrence)
+		protected.POST("/posts/:id/comments/:comment_id/retry", postHandler.RetryComment)
+		protected.GET("/posts/:id/revisions", postHandler.GetPostRevisions)
+		protected.GET("/posts/:id/revisions/diff", postHandler.GetPostRevisionDiff)
+		protected.POST("/posts/:id/revisions/:version/restore", postHandler.RestorePostRevision)
+
+		protected.GET("/tags", postHandler.GetTags)
+		protected.POST("/campaigns", postHandler.CreateCampaign)
+		protected.GET("/campaigns", postHandler.GetCampaigns)
+		protected.GET("/campaigns/:id", postHandler.GetCampaign)
+		protected.PUT("/campaigns/:id", postHandler.UpdateCampaign)
+		protected.DELETE("/campaigns/:id", postHandler.DeleteCampaign)
+		protected.GET("/analytics/rollup", postHandler.GetAnalyticsRollup)
+		protected.POST("/media", postHandler.UploadMedia)
+		protected.POST("/media/uploads", postHandler.CreateMediaUpload)
+		protected.POST("/media/uploads/:id/complete", postHandler.CompleteMediaUpload)
+		protected.POST("/media/resumable", postHandler.CreateResumableUpload)
+		protected.HEAD("/media/resumable/:id", postHandler.GetResumableUploadOffset)
+		protected.PATCH("/media/resumable/:id", postHandler.PatchResumableUpload)
+		protected.DELETE("/media/resumable/:id", postHandler.DeleteResumableUpload)
+		protected.GET("/media", postHandler.GetMediaLibrary)
+		protected.GET("/media/:id", postHandler.GetMedia)
+		protected.PATCH("/media/:id", postHandler.DescribeMedia)
+		protected.DELETE("/media/:id", postHandler.DeleteMedia)
+		protected.POST("/templates", postHandler.CreateTemplate)
+		protected.GET("/templates", postHandler.GetTemplates)
+		protected.GET("/templates/:id", postHandler.GetTemplate)
+		protected.PUT("/templates/:id", postHandler.UpdateTemplate)
+		protected.DELETE("/templates/:id", postHandler.DeleteTemplate)
+		protected.POST("/templates/:id/render", postHandler.RenderTemplate)
+		protected.POST("/snippets", postHandler.CreateSnippet)
+		protected.GET("/snippets", postHandler.GetSnippets)
+		protected.GET("/snippets/:id", postHandler.GetSnippet)
+		protected.PUT("/snippets/:id", postHandler.UpdateSnippet)
+		protected.DELETE("/snippets/:id", postHandler.DeleteSnippet)
+
+		protected.GET("/calendar", postHandler.GetCalendar)
+		protected.GET("/comments/due", postHandler.GetDueComments)
+
+		protected.GET("/queues/:account_id", postHandler.GetQueue)
+		protected.PUT("/queues/:account_id", postHandler.SetQueueSchedule)
+		protected.POST("/queues/:account_id/posts", postHandler.AddToQueue)
+		protected.DELETE("/queues/:account_id/posts/:post_id", postHandler.RemoveFromQueue)
+		protected.PUT("/queues/:account_id/order", postHandler.ReorderQueue)
+		protected.POST("/queues/:account_id/shuffle", postHandler.ShuffleQueue)
+		protected.POST("/queues/:account_id/pause", postHandler.PauseQueue)
+		protected.POST("/queues/:account_id/resume", postHandler.ResumeQueue)
+	}
+
+	// Admin routes
+	admin := router.Group("/api/v1/admin")
+	admin.Use(middleware.JWTAuth(cfg.JWTSecret), middleware.AdminMiddleware())
+	{
+		admin.GET("/storage", postHandler.ListStorageUsage)
+		admin.GET("/users/:id/storage", postHandler.GetUserStorageUsage)
+		admin.PUT("/users/:id/storage", postHandler.UpdateUserStorageQuota)
+		admin.POST("/users/:id/storage/recalculate", postHandler.RecalculateUserStorageUsage)
 	}
 
 	// Start server
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -23,6 +25,24 @@ type Config struct {
 	AWSBucket    string
 	AWSAccessKey string
 	AWSSecretKey string
+	// AWSEndpoint points the S3 backend at an S3-compatible server such as MinIO
+	AWSEndpoint  string
+	AWSPathStyle bool
+
+	// StorageBackend selects where uploads are stored: local, s3 or memory
+	StorageBackend string
+	// UploadDir is the directory of the local storage backend
+	UploadDir string
+	// MediaBaseURL is the public base URL of stored media. The local backend
+	// serves it itself; for S3 it is typically a CDN and defaults to the bucket URL.
+	MediaBaseURL string
+	// UploadStagingDir keeps resumable uploads until they are complete
+	UploadStagingDir string
+
+	// TrashRetention is how long soft-deleted posts are kept before being purged
+	TrashRetention time.Duration
+	// RecurrenceHorizon is how far ahead occurrences of recurring posts are materialized
+	RecurrenceHorizon time.Duration
 
 	// Elasticsearch configuration
 	ElasticsearchURL      string `mapstructure:"ELASTICSEARCH_URL"`
@@ -40,6 +60,16 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid DB_PORT: %v", err)
 	}
 
+	trashRetentionDays, err := strconv.Atoi(getEnv("TRASH_RETENTION_DAYS", "30"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRASH_RETENTION_DAYS: %v", err)
+	}
+
+	recurrenceHorizonDays, err := strconv.Atoi(getEnv("RECURRENCE_HORIZON_DAYS", "28"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECURRENCE_HORIZON_DAYS: %v", err)
+	}
+
 	viper.SetDefault("ELASTICSEARCH_URL", "http://localhost:9200")
 	viper.AutomaticEnv()
 
@@ -62,6 +92,14 @@ func LoadConfig() (*Config, error) {
 	config.AWSBucket = getEnv("AWS_BUCKET", "")
 	config.AWSAccessKey = getEnv("AWS_ACCESS_KEY", "")
 	config.AWSSecretKey = getEnv("AWS_SECRET_KEY", "")
+	config.AWSEndpoint = getEnv("AWS_ENDPOINT", "")
+	config.AWSPathStyle = getEnv("AWS_PATH_STYLE", "false") == "true"
+	config.StorageBackend = getEnv("STORAGE_BACKEND", "local")
+	config.UploadDir = getEnv("UPLOAD_DIR", "uploads")
+	config.MediaBaseURL = getEnv("MEDIA_BASE_URL", "")
+	config.UploadStagingDir = getEnv("UPLOAD_STAGING_DIR", filepath.Join(os.TempDir(), "irm_uploads"))
+	config.TrashRetention = time.Duration(trashRetentionDays) * 24 * time.Hour
+	config.RecurrenceHorizon = time.Duration(recurrenceHorizonDays) * 24 * time.Hour
 	config.ElasticsearchURL = getEnv("ELASTICSEARCH_URL", "http://localhost:9200")
 	config.ElasticsearchUsername = getEnv("ELASTICSEARCH_USERNAME", "")
 	config.ElasticsearchPassword = getEnv("ELASTICSEARCH_PASSWORD", "")
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+// BulkUpdatePosts applies one action to many posts. Posts listed by ID are
+// checked and saved individually within one bulk request and get a result each;
+// a filter applies the action through update_by_query to every matching post
+// it is valid for.
+func (h *Handler) BulkUpdatePosts(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.BulkOperationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Filter != nil {
+		result, err := h.db.UpdatePostsByQuery(userID, &req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update posts"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"action": req.Action, "result": result})
+		return
+	}
+
+	found, err := h.db.GetPostsByIDs(req.PostIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get posts"})
+		return
+	}
+	byID := make(map[string]*models.Post, len(found))
+	for i := range found {
+		if found[i].UserID == userID && found[i].DeletedAt == nil {
+			byID[found[i].ID] = &found[i]
+		}
+	}
+
+	results := make([]models.BulkItemResult, len(req.PostIDs))
+	var previous, posts []*models.Post
+	var indexes []int
+	seen := make(map[string]bool)
+	for i, postID := range req.PostIDs {
+		results[i].PostID = postID
+		post, ok := byID[postID]
+		switch {
+		case seen[postID]:
+			results[i].Error = "duplicate post ID"
+			continue
+		case !ok:
+			results[i].Error = repository.ErrPostNotFound.Error()
+			continue
+		}
+		seen[postID] = true
+
+		before := *post
+		if err := req.Apply(post); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		previous = append(previous, &before)
+		posts = append(posts, post)
+		indexes = append(indexes, i)
+	}
+
+	if len(posts) > 0 {
+		errs, err := h.db.BulkUpdatePosts(previous, posts, userID)
+		if errs == nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update posts"})
+			return
+		}
+		if err != nil {
+			log.Printf("Bulk %s for user %s: %v", req.Action, userID, err)
+		}
+		for j, i := range indexes {
+			switch {
+			case errors.Is(errs[j], repository.ErrVersionConflict):
+				results[i].Error = "post was modified concurrently"
+			case errs[j] != nil:
+				results[i].Error = errs[j].Error()
+			default:
+				results[i].OK = true
+			}
+		}
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.OK {
+			succeeded++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
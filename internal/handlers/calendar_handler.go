@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/utils"
+)
+
+// maxCalendarRange bounds the range of a single calendar request
+const maxCalendarRange = 366 * 24 * time.Hour
+
+// GetCalendar returns the posts of the authenticated user bucketed by day or week.
+// from and to accept RFC3339 or plain dates in the user's timezone.
+func (h *Handler) GetCalendar(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	loc := h.userLocation(userID)
+	if tz := c.Query("timezone"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+	}
+
+	from, err := parseCalendarDate(c.Query("from"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	to, err := parseCalendarDate(c.Query("to"), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxCalendarRange {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from and within one year"})
+		return
+	}
+
+	query := models.CalendarQuery{
+		From:       from,
+		To:         to,
+		Interval:   c.DefaultQuery("interval", "day"),
+		Field:      c.DefaultQuery("field", "scheduled_time"),
+		Timezone:   loc.String(),
+		Platforms:  queryList(c, "platform"),
+		AccountIDs: queryList(c, "account"),
+		Statuses:   queryList(c, "status"),
+		Tags:       models.NormalizeTags(queryList(c, "tag")),
+		Campaigns:  queryList(c, "campaign"),
+	}
+	if query.Interval != "day" && query.Interval != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be day or week"})
+		return
+	}
+	if query.Field != "scheduled_time" && query.Field != "published_at" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "field must be scheduled_time or published_at"})
+		return
+	}
+
+	buckets, err := h.db.GetCalendar(userID, query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timezone": query.Timezone,
+		"interval": query.Interval,
+		"buckets":  buckets,
+	})
+}
+
+// ReschedulePost changes only the scheduled time of a post, for drag and drop in the calendar.
+// If-Match is honoured when sent.
+func (h *Handler) ReschedulePost(c *gin.Context) {
+	var req models.RescheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	value := req.ScheduledTime
+	if value == "" {
+		value = req.LocalScheduledTime
+	}
+	if value == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduled_time or local_scheduled_time is required"})
+		return
+	}
+
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		seqNo, primaryTerm, err := parseETag(ifMatch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+			return
+		}
+		if post.SeqNo != seqNo || post.PrimaryTerm != primaryTerm {
+			h.preconditionFailed(c, post)
+			return
+		}
+	}
+	if post.Status != models.PostStatusDraft && post.Status != models.PostStatusScheduled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft or scheduled posts can be rescheduled"})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	scheduledTime, timezone, err := h.resolveScheduledTime(userID, value, req.Timezone, req.DST)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time: " + err.Error()})
+		return
+	}
+
+	post.ScheduledTime = scheduledTime
+	post.Timezone = timezone
+	if post.SeriesID != "" {
+		post.Detached = true
+	}
+
+	err = h.db.UpdatePost(post, userID)
+	if errors.Is(err, repository.ErrVersionConflict) {
+		if current, err := h.db.GetPost(post.ID); err == nil {
+			h.preconditionFailed(c, current)
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reschedule post"})
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post rescheduled successfully",
+		"post":    h.localizePost(c, post),
+	})
+}
+
+func parseCalendarDate(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return utils.ParseLocalTime(value+"T00:00", loc, utils.DSTCompatible)
+}
+
+// queryList reads a filter given either as repeated parameters or comma separated
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, v := range c.QueryArray(key) {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// postFilterFromQuery reads the post filters shared by listing endpoints:
+// q, tag, campaign, platform and status
+func postFilterFromQuery(c *gin.Context) models.PostFilter {
+	return models.PostFilter{
+		Query:       c.Query("q"),
+		Tags:        models.NormalizeTags(queryList(c, "tag")),
+		CampaignIDs: queryList(c, "campaign"),
+		Platforms:   queryList(c, "platform"),
+		Statuses:    queryList(c, "status"),
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+func (h *Handler) CreateCampaign(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign := &models.Campaign{
+		UserID:    userID,
+		Name:      req.Name,
+		Goal:      req.Goal,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		UTM:       req.UTM,
+	}
+	if err := h.db.CreateCampaign(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Campaign created successfully",
+		"campaign": campaign,
+	})
+}
+
+func (h *Handler) GetCampaigns(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	campaigns, err := h.db.GetUserCampaigns(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get campaigns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+func (h *Handler) GetCampaign(c *gin.Context) {
+	campaign, ok := h.getOwnedCampaign(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"campaign": campaign})
+}
+
+func (h *Handler) UpdateCampaign(c *gin.Context) {
+	var req models.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign, ok := h.getOwnedCampaign(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	campaign.Name = req.Name
+	campaign.Goal = req.Goal
+	campaign.StartDate = req.StartDate
+	campaign.EndDate = req.EndDate
+	campaign.UTM = req.UTM
+	if err := h.db.UpdateCampaign(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Campaign updated successfully",
+		"campaign": campaign,
+	})
+}
+
+func (h *Handler) DeleteCampaign(c *gin.Context) {
+	campaign, ok := h.getOwnedCampaign(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteCampaign(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
+}
+
+// GetTags lists the tags used on the user's posts with their post counts
+func (h *Handler) GetTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tags, err := h.db.GetUserTags(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// GetAnalyticsRollup sums post analytics by tag or campaign (?by=tag|campaign)
+func (h *Handler) GetAnalyticsRollup(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	groupBy := c.DefaultQuery("by", "tag")
+	if groupBy != "tag" && groupBy != "campaign" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by must be tag or campaign"})
+		return
+	}
+
+	rollups, err := h.db.GetAnalyticsRollup(userID, groupBy, postFilterFromQuery(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"by": groupBy, "rollups": rollups})
+}
+
+func (h *Handler) getOwnedCampaign(c *gin.Context, campaignID string) (*models.Campaign, bool) {
+	campaign, err := h.db.GetCampaign(campaignID)
+	if errors.Is(err, repository.ErrCampaignNotFound) || (err == nil && campaign.UserID != c.GetString("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get campaign"})
+		return nil, false
+	}
+	return campaign, true
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+// GetDueComments lists the follow-up comments of the user's published posts that are due now
+func (h *Handler) GetDueComments(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	posts, err := h.db.GetUserPostsWithPendingComments(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comments"})
+		return
+	}
+
+	now := time.Now()
+	due := []models.DueComment{}
+	for i := range posts {
+		due = append(due, posts[i].DueComments(now)...)
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].DueAt.Before(due[j].DueAt) })
+
+	c.JSON(http.StatusOK, gin.H{"comments": due})
+}
+
+// RetryComment puts a failed follow-up comment back into the pending state
+func (h *Handler) RetryComment(c *gin.Context) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	comment := post.Comment(c.Param("comment_id"))
+	if comment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	if comment.Status != models.CommentStatusFailed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only failed comments can be retried"})
+		return
+	}
+
+	comment.Status = models.CommentStatusPending
+	comment.Attempts = 0
+
+	err := h.db.UpdatePost(post, c.GetString("user_id"))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		if current, err := h.db.GetPost(post.ID); err == nil {
+			h.preconditionFailed(c, current)
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment scheduled for retry",
+		"comment": comment,
+	})
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/media"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
+)
+
+// directUploadExpiry is how long an issued upload URL stays valid
+const directUploadExpiry = time.Hour
+
+// CreateMediaUpload authorizes a direct upload to storage and returns the
+// request the client sends the file with. The file is received under a key of
+// its own; once it is uploaded the client completes the upload, which copies
+// it to its content-addressed key and adds it to the media library. When the
+// user already has a file with the declared checksum its asset is returned instead.
+func (h *Handler) CreateMediaUpload(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.CreateMediaUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format, ok := media.FormatOf(req.ContentType)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported content type " + req.ContentType})
+		return
+	}
+	if err := media.CheckSize(format.Type, req.Size); err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	sum := strings.ToLower(req.SHA256)
+	existing, err := h.db.GetUserMediaByHash(userID, sum)
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Media already uploaded",
+			"media":   existing,
+		})
+		return
+	}
+	if !errors.Is(err, repository.ErrMediaNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	presigner, ok := h.storage.(storage.Presigner)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": storage.ErrPresignUnsupported.Error()})
+		return
+	}
+
+	upload := models.MediaUpload{
+		UserID:      userID,
+		Key:         directUploadKey(userID, format),
+		FileName:    media.SanitizeFileName(req.FileName, format),
+		ContentType: format.ContentType,
+		Size:        req.Size,
+		SHA256:      sum,
+	}
+	request, err := presigner.PresignPut(upload.Key, upload.ContentType, upload.Size, directUploadExpiry)
+	if errors.Is(err, storage.ErrPresignUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload URL"})
+		return
+	}
+	upload.ExpiresAt = request.ExpiresAt
+
+	if err := h.db.CreateMediaUpload(&upload); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"upload_id": upload.ID,
+		"upload":    request,
+	})
+}
+
+// CompleteMediaUpload checks that the uploaded file has the declared size,
+// checksum and format and adds it to the media library. A mismatching file is
+// removed so the client can upload it again while the URL is valid. The
+// upload is kept as completed until its URL expires, so later uploads to the
+// URL are rejected and cleaned up.
+func (h *Handler) CompleteMediaUpload(c *gin.Context) {
+	userID := c.GetString("user_id")
+	upload, err := h.db.GetMediaUpload(c.Param("id"))
+	if errors.Is(err, repository.ErrMediaUploadNotFound) || (err == nil && upload.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload"})
+		return
+	}
+	if upload.CompletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has already been completed"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	file, err := h.storage.Open(ctx, upload.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": "File has not been uploaded"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	hash := sha256.New()
+	header := &prefixWriter{limit: media.SniffLen}
+	var size byteCounter
+	content := io.TeeReader(file, io.MultiWriter(hash, header, &size))
+
+	// Video metadata is read while the file streams through the hash
+	var video *media.VideoInfo
+	var videoErr error
+	if models.MediaTypeFromContentType(upload.ContentType) == "video" {
+		video, videoErr = media.ParseVideo(content, upload.Size)
+	}
+	_, err = io.Copy(io.Discard, content)
+	file.Close()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	var mismatch string
+	if int64(size) != upload.Size || hex.EncodeToString(hash.Sum(nil)) != upload.SHA256 {
+		mismatch = "Uploaded file does not match the declared size and checksum"
+	} else if format, err := media.Sniff(header.buf); err != nil {
+		mismatch = err.Error()
+	} else if format.ContentType != upload.ContentType {
+		mismatch = fmt.Sprintf("Uploaded file is %s, not the declared %s", format.ContentType, upload.ContentType)
+	} else if videoErr != nil {
+		mismatch = videoErr.Error()
+	}
+	if mismatch != "" {
+		h.removeDirectUpload(ctx, upload)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": mismatch})
+		return
+	}
+
+	// The same file may have been added to the library since the upload started
+	if existing, err := h.db.GetUserMediaByHash(userID, upload.SHA256); err == nil {
+		h.finishDirectUpload(ctx, upload)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Media already uploaded",
+			"media":   existing,
+		})
+		return
+	} else if !errors.Is(err, repository.ErrMediaNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media"})
+		return
+	}
+
+	// The verified file moves to its content-addressed key, where later
+	// uploads to the signed URL cannot reach it
+	format, _ := media.FormatOf(upload.ContentType)
+	key := contentKey(userID, upload.SHA256, format)
+	stored, err := h.copyUpload(ctx, upload, key)
+	if err != nil {
+		if errors.Is(err, errUploadChanged) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Uploaded file changed while the upload was being completed"})
+			return
+		}
+		if errors.Is(err, media.ErrUnsupported) {
+			h.removeDirectUpload(ctx, upload)
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	asset := models.MediaAsset{
+		UserID:      userID,
+		Key:         key,
+		URL:         h.storage.URL(key),
+		Type:        models.MediaTypeFromContentType(upload.ContentType),
+		FileName:    upload.FileName,
+		ContentType: upload.ContentType,
+		Size:        stored,
+		SHA256:      upload.SHA256,
+	}
+	if video != nil {
+		asset.Width, asset.Height, asset.Video = video.Width, video.Height, videoMetadata(video)
+	}
+	if err := h.db.CreateMedia(&asset); err != nil {
+		if err := h.removeUnusedFile(ctx, key); err != nil {
+			log.Printf("Failed to remove unsaved upload %s: %v", key, err)
+		}
+		if errors.Is(err, models.ErrStorageQuotaExceeded) {
+			h.removeDirectUpload(ctx, upload)
+		}
+		uploadError(c, err)
+		return
+	}
+	h.finishDirectUpload(ctx, upload)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Media uploaded successfully",
+		"media":   asset,
+	})
+}
+
+// ReceiveDirectUpload stores the body of a PUT to a signed upload URL of the
+// local storage backend. The signature authenticates the request, so the
+// route is not behind the JWT middleware.
+func (h *Handler) ReceiveDirectUpload(c *gin.Context) {
+	local, ok := h.storage.(*storage.Local)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	size, err := local.VerifyUpload(key, c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	// The URL stays valid after the upload is completed or abandoned
+	upload, err := h.db.GetMediaUploadByKey(key)
+	if errors.Is(err, repository.ErrMediaUploadNotFound) || (err == nil && upload.CompletedAt != nil) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is no longer open"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload"})
+		return
+	}
+	if c.Request.ContentLength != size {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content-Length does not match the signed size"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, size)
+	if err := local.Put(c.Request.Context(), key, body, size, c.ContentType()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// directUploadKey is where a direct upload is received before it is verified
+func directUploadKey(userID string, format media.Format) string {
+	return userID + "/uploads/" + uuid.New().String() + format.Ext
+}
+
+// errUploadChanged is returned when a received file is replaced while it is copied
+var errUploadChanged = errors.New("uploaded file changed while the upload was being completed")
+
+// copyUpload copies the verified file of an upload to its final key, images
+// without their metadata, and returns the size of the copy. The file is
+// hashed again, as it can be uploaded anew until the upload is completed.
+func (h *Handler) copyUpload(ctx context.Context, upload *models.MediaUpload, key string) (int64, error) {
+	file, err := h.storage.Open(ctx, upload.Key)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	content := io.TeeReader(file, hash)
+	if format, _ := media.FormatOf(upload.ContentType); format.Type == "image" {
+		data, err := io.ReadAll(io.LimitReader(content, media.MaxImageSize+1))
+		if err != nil {
+			return 0, err
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != upload.SHA256 {
+			return 0, errUploadChanged
+		}
+		if data, err = media.CleanImage(data, format); err != nil {
+			return 0, err
+		}
+		return int64(len(data)), h.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), upload.ContentType)
+	}
+
+	if err := h.storage.Put(ctx, key, content, upload.Size, upload.ContentType); err != nil {
+		return 0, err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != upload.SHA256 {
+		if err := h.removeUnusedFile(ctx, key); err != nil {
+			log.Printf("Failed to remove changed upload %s: %v", key, err)
+		}
+		return 0, errUploadChanged
+	}
+	return upload.Size, nil
+}
+
+// removeDirectUpload removes the file received for a direct upload
+func (h *Handler) removeDirectUpload(ctx context.Context, upload *models.MediaUpload) {
+	if err := storage.Remove(ctx, h.storage, upload.Key, ""); err != nil {
+		log.Printf("Failed to remove upload %s: %v", upload.Key, err)
+	}
+}
+
+// finishDirectUpload removes the received file of an upload that was added to
+// the library and marks it completed. The upload reaper deletes it, and any
+// file uploaded to its URL after all, once the URL has expired.
+func (h *Handler) finishDirectUpload(ctx context.Context, upload *models.MediaUpload) {
+	h.removeDirectUpload(ctx, upload)
+	now := time.Now()
+	upload.CompletedAt = &now
+	if err := h.db.UpdateMediaUpload(upload); err != nil {
+		log.Printf("Failed to complete upload %s: %v", upload.ID, err)
+	}
+}
+
+// prefixWriter keeps the first bytes written to it
+type prefixWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if n := w.limit - len(w.buf); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		w.buf = append(w.buf, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// byteCounter counts the bytes written to it
+type byteCounter int64
+
+func (n *byteCounter) Write(p []byte) (int, error) {
+	*n += byteCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// DuplicatePost creates a draft copy of a post that shares its media and links
+// back to it. The copy can be retargeted to other platforms or given a new schedule.
+func (h *Handler) DuplicatePost(c *gin.Context) {
+	// The body is optional; an empty one, chunked or not, keeps the source as is
+	var req models.DuplicatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	source, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	post, err := source.Duplicate()
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Platforms) > 0 {
+		post.Platforms = req.Platforms
+	}
+	if !req.ScheduledTime.IsZero() {
+		if err := validTimezone(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time: " + err.Error()})
+			return
+		}
+		post.ScheduledTime = req.ScheduledTime.UTC()
+		post.Timezone = req.Timezone
+	}
+	if req.LocalScheduledTime != "" {
+		scheduledTime, timezone, err := h.resolveScheduledTime(post.UserID, req.LocalScheduledTime, req.Timezone, req.DST)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time: " + err.Error()})
+			return
+		}
+		post.ScheduledTime = scheduledTime
+		post.Timezone = timezone
+	}
+	if err := post.ValidateForPlatforms(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.CreatePost(post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to duplicate post"})
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Post duplicated successfully",
+		"post":    h.localizePost(c, post),
+	})
+}
+
+// GetPostCopies compares the analytics of a post with those of its duplicates
+func (h *Handler) GetPostCopies(c *gin.Context) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	source, copies, err := h.db.GetPostCopies(post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post copies"})
+		return
+	}
+
+	loc := h.userLocation(post.UserID)
+	source.Post = source.Post.InLocation(loc)
+	for i := range copies {
+		copies[i].Post = copies[i].Post.InLocation(loc)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"source": source,
+		"copies": copies,
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// postETag builds the ETag of a post from its Elasticsearch sequence number and primary term
+func postETag(post *models.Post) string {
+	return fmt.Sprintf(`"%d-%d"`, post.SeqNo, post.PrimaryTerm)
+}
+
+// queueETag builds the ETag of a posting queue the same way as postETag
+func queueETag(queue *models.PostingQueue) string {
+	return fmt.Sprintf(`"%d-%d"`, queue.SeqNo, queue.PrimaryTerm)
+}
+
+// parseETag extracts the sequence number and primary term from an If-Match header value
+func parseETag(etag string) (seqNo, primaryTerm int, err error) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	etag = strings.Trim(etag, `"`)
+
+	parts := strings.Split(etag, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid etag %q", etag)
+	}
+	if seqNo, err = strconv.Atoi(parts[0]); err != nil {
+		return 0, 0, fmt.Errorf("invalid etag %q", etag)
+	}
+	if primaryTerm, err = strconv.Atoi(parts[1]); err != nil {
+		return 0, 0, fmt.Errorf("invalid etag %q", etag)
+	}
+	return seqNo, primaryTerm, nil
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/utils"
+)
+
+// exportWriter writes the rows of an export in one output format
+type exportWriter interface {
+	WriteHeader() error
+	WriteRow(row *models.PostExport) error
+	// Flush is called after every page so rows reach the client as they are read
+	Flush() error
+	Close() error
+}
+
+// ExportPosts streams the posts of the authenticated user joined with their
+// analytics as csv, ndjson or xlsx (?format=). from and to bound the scheduled
+// time and accept RFC3339 or plain dates in the user's timezone; the listing
+// filters tag, campaign, platform and status apply as well.
+func (h *Handler) ExportPosts(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	loc := h.userLocation(userID)
+	query := models.ExportQuery{Filter: postFilterFromQuery(c)}
+	if query.Filter.Query != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Full text search is not supported for exports"})
+		return
+	}
+	var err error
+	if from := c.Query("from"); from != "" {
+		if query.From, err = parseCalendarDate(from, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = parseCalendarDate(to, loc); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return
+		}
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.To.After(query.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv, ndjson or xlsx"})
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="posts-%s.%s"`, time.Now().In(loc).Format("2006-01-02"), format))
+	c.Status(http.StatusOK)
+
+	var w exportWriter
+	switch format {
+	case "csv":
+		w = &csvExportWriter{w: csv.NewWriter(c.Writer), loc: loc}
+	case "ndjson":
+		w = &ndjsonExportWriter{enc: json.NewEncoder(c.Writer), loc: loc}
+	case "xlsx":
+		xlsx, err := utils.NewXLSXWriter(c.Writer, "Posts")
+		if err != nil {
+			log.Printf("Export for user %s: %v", userID, err)
+			return
+		}
+		w = &xlsxExportWriter{w: xlsx, loc: loc}
+	}
+
+	// Once the first bytes are sent the status can no longer change, so a
+	// failure part way through leaves a truncated file and is only logged
+	err = w.WriteHeader()
+	if err == nil {
+		err = h.db.StreamPostExports(userID, query, func(rows []models.PostExport) error {
+			for i := range rows {
+				if err := w.WriteRow(&rows[i]); err != nil {
+					return err
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		log.Printf("Export for user %s: %v", userID, err)
+	}
+}
+
+type csvExportWriter struct {
+	w   *csv.Writer
+	loc *time.Location
+}
+
+func (e *csvExportWriter) WriteHeader() error {
+	return e.w.Write(models.ExportColumns)
+}
+
+func (e *csvExportWriter) WriteRow(row *models.PostExport) error {
+	values := row.Values(e.loc)
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = fmt.Sprint(v)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	return e.Flush()
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+	loc *time.Location
+}
+
+func (e *ndjsonExportWriter) WriteHeader() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) WriteRow(row *models.PostExport) error {
+	localized := *row
+	localized.Post = row.Post.InLocation(e.loc)
+	return e.enc.Encode(localized)
+}
+
+func (e *ndjsonExportWriter) Flush() error {
+	return nil
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+type xlsxExportWriter struct {
+	w   *utils.XLSXWriter
+	loc *time.Location
+}
+
+func (e *xlsxExportWriter) WriteHeader() error {
+	header := make([]interface{}, len(models.ExportColumns))
+	for i, column := range models.ExportColumns {
+		header[i] = column
+	}
+	return e.w.WriteRow(header)
+}
+
+func (e *xlsxExportWriter) WriteRow(row *models.PostExport) error {
+	return e.w.WriteRow(row.Values(e.loc))
+}
+
+func (e *xlsxExportWriter) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *xlsxExportWriter) Close() error {
+	return e.w.Close()
+}
@@ -7,12 +7,15 @@ import (
 	"github.com/priince9381/irm_backend/internal/config"
 	"github.com/priince9381/irm_backend/internal/models"
 	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Handler struct {
-	db  *repository.ElasticsearchDB
-	cfg *config.Config
+	db      *repository.ElasticsearchDB
+	cfg     *config.Config
+	storage storage.Storage
+	staging *storage.Staging
 }
 
 type RegisterRequest struct {
@@ -26,10 +29,12 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func NewHandler(db *repository.ElasticsearchDB, cfg *config.Config) *Handler {
+func NewHandler(db *repository.ElasticsearchDB, cfg *config.Config, store storage.Storage, staging *storage.Staging) *Handler {
 	return &Handler{
-		db:  db,
-		cfg: cfg,
+		db:      db,
+		cfg:     cfg,
+		storage: store,
+		staging: staging,
 	}
 }
 
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// maxImportSize caps the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+// importFormOverhead allows for the multipart framing around an uploaded file
+const importFormOverhead = 1 << 20
+
+// ImportPosts creates posts in bulk from a CSV or NDJSON file, uploaded as the
+// "file" form field or sent as the request body. The format comes from ?format=,
+// the file extension or the content type. With ?dry_run=true the rows are only
+// validated. Otherwise the valid rows are created and every failed row is
+// reported with its error.
+func (h *Handler) ImportPosts(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	loc := h.userLocation(userID)
+	dst := c.Query("dst")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize+importFormOverhead)
+
+	var body io.Reader
+	var name string
+	file, err := c.FormFile("file")
+	if isTooLarge(err) || (err == nil && file.Size > maxImportSize) {
+		importTooLarge(c)
+		return
+	}
+	if err == nil {
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return
+		}
+		defer f.Close()
+		body, name = f, file.Filename
+	} else {
+		body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	}
+
+	var rows []models.ImportRow
+	switch importFormat(c.Query("format"), name, c.ContentType()) {
+	case "csv":
+		rows, err = models.ParseImportCSV(body)
+	case "ndjson":
+		rows, err = models.ParseImportNDJSON(body)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or ndjson"})
+		return
+	}
+	if isTooLarge(err) {
+		importTooLarge(c)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No rows to import"})
+		return
+	}
+
+	result := models.ImportResult{
+		DryRun: c.Query("dry_run") == "true",
+		Total:  len(rows),
+		Errors: []models.ImportRowError{},
+	}
+	library, err := h.importMedia(userID, rows)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get media"})
+		return
+	}
+
+	var posts []*models.Post
+	var postRows []int
+	for i, row := range rows {
+		post, err := importPost(userID, row, library, loc, dst)
+		if err != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: i + 1, Error: err.Error()})
+			continue
+		}
+		posts = append(posts, post)
+		postRows = append(postRows, i+1)
+	}
+	result.Valid = len(posts)
+
+	if result.DryRun || len(posts) == 0 {
+		c.JSON(http.StatusOK, gin.H{"result": result})
+		return
+	}
+
+	errs, err := h.db.BulkCreatePosts(posts)
+	if errs == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import posts"})
+		return
+	}
+	if err != nil {
+		log.Printf("Import for user %s: %v", userID, err)
+	}
+	for i, post := range posts {
+		if errs[i] != nil {
+			result.Errors = append(result.Errors, models.ImportRowError{Row: postRows[i], Error: "failed to save post: " + errs[i].Error()})
+			continue
+		}
+		result.Imported++
+		result.PostIDs = append(result.PostIDs, post.ID)
+	}
+
+	status := http.StatusCreated
+	if result.Imported == 0 {
+		status = http.StatusInternalServerError
+	}
+	c.JSON(status, gin.H{"result": result})
+}
+
+// importMedia fetches the library media referenced by the rows. Media of
+// other users is left out.
+func (h *Handler) importMedia(userID string, rows []models.ImportRow) (map[string]models.MediaAsset, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, row := range rows {
+		for _, id := range row.MediaIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	assets, err := h.db.GetMediaByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for id, asset := range assets {
+		if asset.UserID != userID {
+			delete(assets, id)
+		}
+	}
+	return assets, nil
+}
+
+// importPost validates an import row and builds its post, attaching media from
+// library. Local scheduled times without a timezone column are read in loc.
+func importPost(userID string, row models.ImportRow, library map[string]models.MediaAsset, loc *time.Location, dst string) (*models.Post, error) {
+	if row.Title == "" {
+		return nil, errors.New("title is required")
+	}
+	if row.Content == "" {
+		return nil, errors.New("content is required")
+	}
+	if len(row.Platforms) == 0 {
+		return nil, errors.New("at least one platform is required")
+	}
+
+	post := &models.Post{
+		Title:     row.Title,
+		Content:   row.Content,
+		UserID:    userID,
+		Platforms: row.Platforms,
+		Links:     row.Links,
+		Tags:      models.NormalizeTags(row.Tags),
+	}
+
+	if row.ScheduledTime != "" {
+		t, timezone, err := resolveScheduledTimeIn(loc, row.ScheduledTime, row.Timezone, dst)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduled time: %v", err)
+		}
+		post.ScheduledTime = t
+		post.Timezone = timezone
+	}
+
+	status := row.Status
+	if status == "" {
+		status = models.PostStatusDraft
+		if !post.ScheduledTime.IsZero() {
+			status = models.PostStatusScheduled
+		}
+	}
+	if status != models.PostStatusDraft && status != models.PostStatusScheduled {
+		return nil, fmt.Errorf("status must be %s or %s", models.PostStatusDraft, models.PostStatusScheduled)
+	}
+	if status == models.PostStatusScheduled {
+		if post.ScheduledTime.IsZero() {
+			return nil, errors.New("scheduled time is required for scheduled posts")
+		}
+		if !post.ScheduledTime.After(time.Now()) {
+			return nil, errors.New("scheduled time is in the past")
+		}
+	}
+	if err := post.TransitionTo(status); err != nil {
+		return nil, err
+	}
+
+	if len(row.MediaURLs) > 0 {
+		return nil, errors.New("media_urls are not supported, upload the files to the media library and list their IDs in media_ids")
+	}
+	var unknown []string
+	for _, id := range row.MediaIDs {
+		asset, ok := library[id]
+		if !ok {
+			unknown = append(unknown, id)
+			continue
+		}
+		post.MediaFiles = append(post.MediaFiles, asset.Reference())
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown media: %s", strings.Join(unknown, ", "))
+	}
+
+	if err := post.ValidateForPlatforms(); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// importFormat picks the import format from the explicit parameter, the file
+// name or the content type, in that order
+func importFormat(format, filename, contentType string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	switch contentType {
+	case "text/csv":
+		return "csv"
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return "ndjson"
+	}
+	return ""
+}
+
+// isTooLarge reports whether reading a request body failed at its size limit
+func isTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+func importTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": fmt.Sprintf("Import files are limited to %d MB", maxImportSize>>20),
+	})
+}
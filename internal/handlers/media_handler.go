@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/media"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
+)
+
+// UploadMedia adds the files uploaded under "files" to the media library.
+// Tags, alt text and caption given in the form apply to every new file; files
+// uploaded before return their existing assets, descriptions unchanged.
+func (h *Handler) UploadMedia(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one file is required"})
+		return
+	}
+
+	assets, created, err := h.saveMediaUploads(c, userID, form.File["files"])
+	if err != nil {
+		h.discardMedia(created)
+		uploadError(c, err)
+		return
+	}
+
+	tags := models.NormalizeTags(c.PostFormArray("tags"))
+	altText, caption := c.PostForm("alt_text"), c.PostForm("caption")
+	if len(tags) > 0 || altText != "" || caption != "" {
+		described := make(map[string]bool, len(created))
+		for i := range created {
+			created[i].Tags = tags
+			created[i].AltText = altText
+			created[i].Caption = caption
+			if err := h.db.UpdateMedia(&created[i]); err != nil {
+				h.discardMedia(created)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save media"})
+				return
+			}
+			described[created[i].ID] = true
+		}
+		for i := range assets {
+			if described[assets[i].ID] {
+				assets[i].Tags, assets[i].AltText, assets[i].Caption = tags, altText, caption
+			}
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Media uploaded successfully",
+		"media":   assets,
+	})
+}
+
+// GetMediaLibrary lists the media of the authenticated user, optionally
+// searched by q (file name, alt text, caption), type and tag
+func (h *Handler) GetMediaLibrary(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	assets, err := h.db.SearchUserMedia(userID, models.MediaQuery{
+		Query: c.Query("q"),
+		Type:  c.Query("type"),
+		Tags:  models.NormalizeTags(queryList(c, "tag")),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get media"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"media": assets})
+}
+
+func (h *Handler) GetMedia(c *gin.Context) {
+	asset, ok := h.getOwnedMedia(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"media": asset})
+}
+
+// DescribeMedia updates the alt text, caption and tags of an asset
+func (h *Handler) DescribeMedia(c *gin.Context) {
+	var req models.DescribeMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	asset, ok := h.getOwnedMedia(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if req.AltText != nil {
+		asset.AltText = *req.AltText
+	}
+	if req.Caption != nil {
+		asset.Caption = *req.Caption
+	}
+	if req.Tags != nil {
+		asset.Tags = models.NormalizeTags(req.Tags)
+	}
+	if err := h.db.UpdateMedia(asset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update media"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Media updated successfully",
+		"media":   asset,
+	})
+}
+
+// DeleteMedia removes an asset and its file. Media still used by a post,
+// including posts in the trash, cannot be deleted.
+func (h *Handler) DeleteMedia(c *gin.Context) {
+	asset, ok := h.getOwnedMedia(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	inUse, err := h.db.MediaInUse(asset.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media"})
+		return
+	}
+	if inUse {
+		c.JSON(http.StatusConflict, gin.H{"error": "Media is used by a post"})
+		return
+	}
+
+	if err := h.db.DeleteMedia(asset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media"})
+		return
+	}
+	if err := h.removeMediaFiles(c.Request.Context(), asset); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media file"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
+}
+
+// saveMediaUploads adds uploaded files to the user's media library. Files the
+// user uploaded before resolve to their existing assets; created holds the
+// assets that are new, which are the ones to discard if the request fails.
+func (h *Handler) saveMediaUploads(c *gin.Context, userID string, files []*multipart.FileHeader) (assets, created []models.MediaAsset, err error) {
+	for _, file := range files {
+		asset, isNew, err := h.saveUpload(c, userID, file)
+		if err != nil {
+			return assets, created, err
+		}
+		assets = append(assets, *asset)
+		if isNew {
+			created = append(created, *asset)
+		}
+	}
+	return assets, created, nil
+}
+
+// removeMediaFiles deletes the stored file of an asset and its renditions
+// unless another asset or a post still uses the file
+func (h *Handler) removeMediaFiles(ctx context.Context, asset *models.MediaAsset) error {
+	if asset.Key != "" {
+		inUse, err := h.db.MediaKeyInUse(asset.Key, asset.ID)
+		if err != nil || inUse {
+			return err
+		}
+	}
+	for _, rendition := range asset.Renditions {
+		if err := storage.Remove(ctx, h.storage, rendition.Key, ""); err != nil {
+			return err
+		}
+	}
+	return storage.Remove(ctx, h.storage, asset.Key, asset.URL)
+}
+
+// videoMetadata converts the metadata read from a video container
+func videoMetadata(info *media.VideoInfo) *models.VideoInfo {
+	return &models.VideoInfo{
+		DurationSeconds: info.DurationSeconds,
+		VideoCodec:      info.VideoCodec,
+		AudioCodec:      info.AudioCodec,
+		FrameRate:       info.FrameRate,
+		Bitrate:         info.Bitrate,
+	}
+}
+
+// contentKey is the storage key of a file of a user with the given SHA-256.
+// Keys are scoped per user, so only a user's own uploads are deduplicated.
+func contentKey(userID, sha256 string, format media.Format) string {
+	return userID + "/" + sha256 + format.Ext
+}
+
+// cleanImage reads an uploaded image and removes its metadata, which is done
+// before the image is first stored. The key of the image remains the hash of
+// the uploaded file, so uploading it again finds the existing asset.
+func cleanImage(r io.Reader, format media.Format) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, media.MaxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	return media.CleanImage(data, format)
+}
+
+// uploadError responds to a failed upload, explaining why a file was rejected
+func uploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrUnsupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrTooLarge), errors.Is(err, models.ErrStorageQuotaExceeded):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	}
+}
+
+// removeUnusedFile deletes a stored file unless an asset or a post uses it
+func (h *Handler) removeUnusedFile(ctx context.Context, key string) error {
+	inUse, err := h.db.MediaKeyInUse(key, "")
+	if err != nil || inUse {
+		return err
+	}
+	return storage.Remove(ctx, h.storage, key, "")
+}
+
+// discardMedia removes assets, and their files, that were uploaded for a
+// request that failed
+func (h *Handler) discardMedia(assets []models.MediaAsset) {
+	for _, asset := range assets {
+		h.db.DeleteMedia(&asset)
+		h.removeMediaFiles(context.Background(), &asset)
+	}
+}
+
+// resolveMedia turns library media IDs into post media entries. Unknown IDs
+// and media of other users are rejected.
+func (h *Handler) resolveMedia(c *gin.Context, userID string, mediaIDs []string) ([]models.Media, bool) {
+	if len(mediaIDs) == 0 {
+		return nil, true
+	}
+
+	assets, err := h.db.GetMediaByIDs(mediaIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get media"})
+		return nil, false
+	}
+
+	media := make([]models.Media, 0, len(mediaIDs))
+	var unknown []string
+	for _, id := range mediaIDs {
+		asset, ok := assets[id]
+		if !ok || asset.UserID != userID {
+			unknown = append(unknown, id)
+			continue
+		}
+		media = append(media, asset.Reference())
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown media: " + strings.Join(unknown, ", ")})
+		return nil, false
+	}
+	return media, true
+}
+
+func mediaReferences(assets []models.MediaAsset) []models.Media {
+	media := make([]models.Media, len(assets))
+	for i := range assets {
+		media[i] = assets[i].Reference()
+	}
+	return media
+}
+
+func (h *Handler) getOwnedMedia(c *gin.Context, mediaID string) (*models.MediaAsset, bool) {
+	asset, err := h.db.GetMedia(mediaID)
+	if errors.Is(err, repository.ErrMediaNotFound) || (err == nil && asset.UserID != c.GetString("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get media"})
+		return nil, false
+	}
+	return asset, true
+}
@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/media"
 	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
 	"gorm.io/gorm"
 )
 
@@ -59,88 +67,150 @@ func (h *Handler) CreatePost(c *gin.Context) {
 
 	links := c.PostFormArray("links")
 
-	// Parse scheduled time if provided
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse scheduled time if provided, either RFC3339 or a local wall-clock
+	// time in the given timezone (the user's preferred one by default)
 	var scheduledTime time.Time
+	var timezone string
 	if timeStr := c.PostForm("scheduled_time"); timeStr != "" {
 		var err error
-		scheduledTime, err = time.Parse(time.RFC3339, timeStr)
+		scheduledTime, timezone, err = h.resolveScheduledTime(userID, timeStr, c.PostForm("timezone"), c.PostForm("dst"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time format"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time: " + err.Error()})
 			return
 		}
 	}
 
 	status := c.PostForm("status")
 	if status == "" {
-		status = "draft"
+		status = models.PostStatusDraft
+	}
+	if status == models.PostStatusScheduled && scheduledTime.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scheduled time is required for scheduled posts"})
+		return
 	}
 
-	userID := c.GetString("user_id")
-	if userID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	post := &models.Post{
+		Title:         title,
+		Content:       content,
+		UserID:        userID,
+		Platforms:     platforms,
+		Links:         links,
+		ScheduledTime: scheduledTime,
+		Timezone:      timezone,
+		Tags:          models.NormalizeTags(c.PostFormArray("tags")),
+	}
+
+	if campaignID := c.PostForm("campaign_id"); campaignID != "" {
+		if _, ok := h.getOwnedCampaign(c, campaignID); !ok {
+			return
+		}
+		post.CampaignID = campaignID
+	}
+
+	if err := post.TransitionTo(status); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Handle file uploads
-	form, _ := c.MultipartForm()
-	files := form.File["files"]
-
-	var mediaFiles []models.Media
-	if len(files) > 0 {
-		uploadDir := "uploads/"
-		// Create uploads directory if it doesn't exist
-		if err := os.MkdirAll(uploadDir, 0755); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+	// Thread or carousel parts come as a JSON array in the "parts" field,
+	// with the media of part i uploaded under "part_<i>_files" or referenced by its "media_ids"
+	if partsJSON := c.PostForm("parts"); partsJSON != "" {
+		if err := json.Unmarshal([]byte(partsJSON), &post.Parts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parts format"})
 			return
 		}
+	}
 
-		for _, file := range files {
-			// Generate unique filename
-			filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), file.Filename)
-			filepath := uploadDir + filename
+	// Follow-up comments come as a JSON array in the "comments" field
+	if commentsJSON := c.PostForm("comments"); commentsJSON != "" {
+		var reqs []models.FollowUpCommentRequest
+		if err := json.Unmarshal([]byte(commentsJSON), &reqs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comments format"})
+			return
+		}
+		comments, err := models.NewFollowUpComments(reqs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		post.Comments = comments
+	}
 
-			// Save the file
-			if err := c.SaveUploadedFile(file, filepath); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
-				return
-			}
+	// Library media is referenced by ID in "media_ids", and in the "media_ids" of each part
+	mediaFiles, ok := h.resolveMedia(c, userID, c.PostFormArray("media_ids"))
+	if !ok {
+		return
+	}
+	post.MediaFiles = mediaFiles
+	for i := range post.Parts {
+		post.Parts[i].PlatformIDs = nil
+		partMedia, ok := h.resolveMedia(c, userID, post.Parts[i].MediaIDs)
+		if !ok {
+			return
+		}
+		post.Parts[i].MediaFiles = partMedia
+		post.Parts[i].MediaIDs = nil
+	}
 
-			// Determine media type
-			mediaType := "image"
-			if strings.HasPrefix(file.Header.Get("Content-Type"), "video/") {
-				mediaType = "video"
-			}
+	// Uploaded files are added to the media library
+	form, _ := c.MultipartForm()
+
+	// Files the user uploaded before resolve to their existing assets, only
+	// the new ones are discarded when the post cannot be created
+	uploaded, created, err := h.saveMediaUploads(c, userID, form.File["files"])
+	if err != nil {
+		h.discardMedia(created)
+		uploadError(c, err)
+		return
+	}
+	post.MediaFiles = append(post.MediaFiles, mediaReferences(uploaded)...)
 
-			mediaFiles = append(mediaFiles, models.Media{
-				URL:      filepath,
-				Type:     mediaType,
-				FileName: filename,
-			})
+	for i := range post.Parts {
+		partUploads, partCreated, err := h.saveMediaUploads(c, userID, form.File[fmt.Sprintf("part_%d_files", i)])
+		created = append(created, partCreated...)
+		if err != nil {
+			h.discardMedia(created)
+			uploadError(c, err)
+			return
 		}
+		post.Parts[i].MediaFiles = append(post.Parts[i].MediaFiles, mediaReferences(partUploads)...)
 	}
 
-	post := &models.Post{
-		Title:         title,
-		Content:       content,
-		UserID:        userID,
-		Platforms:     platforms,
-		MediaFiles:    mediaFiles,
-		Links:         links,
-		ScheduledTime: scheduledTime,
-		Status:        status,
+	if err := post.ValidateForPlatforms(); err != nil {
+		h.discardMedia(created)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	if err := h.db.CreatePost(post); err != nil {
+		h.discardMedia(created)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
 		return
 	}
 
+	c.Header("ETag", postETag(post))
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Post created successfully",
-		"post":    post,
+		"post":    h.localizePost(c, post),
 	})
 }
 
+func (h *Handler) GetPost(c *gin.Context) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, gin.H{"post": h.localizePost(c, post)})
+}
+
 func (h *Handler) GetPosts(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -148,13 +218,13 @@ func (h *Handler) GetPosts(c *gin.Context) {
 		return
 	}
 
-	posts, err := h.db.GetUserPosts(userID.(string))
+	posts, err := h.db.GetUserPosts(userID.(string), postFilterFromQuery(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get posts"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	c.JSON(http.StatusOK, gin.H{"posts": h.localizePosts(c, posts)})
 }
 
 func (h *Handler) UpdatePost(c *gin.Context) {
@@ -164,14 +234,167 @@ func (h *Handler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	var req CreatePostRequest
+	var req models.UpdatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// TODO: Implement post update logic
-	c.JSON(http.StatusOK, gin.H{"message": "Post updated successfully"})
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return
+	}
+	seqNo, primaryTerm, err := parseETag(ifMatch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return
+	}
+
+	post, ok := h.getOwnedPost(c, postID)
+	if !ok {
+		return
+	}
+	if post.SeqNo != seqNo || post.PrimaryTerm != primaryTerm {
+		h.preconditionFailed(c, post)
+		return
+	}
+	// The scheduler owns a post while publishing it; once published only its
+	// status may still change
+	switch {
+	case post.Status == models.PostStatusPublishing:
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is being published and cannot be edited"})
+		return
+	case post.Status == models.PostStatusPublished && req.ChangesContent():
+		c.JSON(http.StatusConflict, gin.H{"error": "Published posts cannot be edited"})
+		return
+	}
+
+	if req.Title != "" {
+		post.Title = req.Title
+	}
+	if req.Content != "" {
+		post.Content = req.Content
+	}
+	if len(req.Platforms) > 0 {
+		post.Platforms = req.Platforms
+	}
+	if req.Links != nil {
+		post.Links = req.Links
+	}
+	if req.MediaIDs != nil {
+		mediaFiles, ok := h.resolveMedia(c, post.UserID, req.MediaIDs)
+		if !ok {
+			return
+		}
+		post.MediaFiles = mediaFiles
+	}
+	if req.Parts != nil {
+		// Part media comes from the library only, as in CreatePost
+		for i := range req.Parts {
+			req.Parts[i].PlatformIDs = nil
+			partMedia, ok := h.resolveMedia(c, post.UserID, req.Parts[i].MediaIDs)
+			if !ok {
+				return
+			}
+			req.Parts[i].MediaFiles = partMedia
+			req.Parts[i].MediaIDs = nil
+		}
+		post.Parts = req.Parts
+	}
+	if req.Tags != nil {
+		post.Tags = models.NormalizeTags(req.Tags)
+	}
+	if req.CampaignID != nil {
+		if *req.CampaignID != "" {
+			if _, ok := h.getOwnedCampaign(c, *req.CampaignID); !ok {
+				return
+			}
+		}
+		post.CampaignID = *req.CampaignID
+	}
+	if req.Comments != nil {
+		comments, err := models.NewFollowUpComments(req.Comments)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		post.Comments = comments
+	}
+	if !req.ScheduledTime.IsZero() {
+		if err := validTimezone(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time: " + err.Error()})
+			return
+		}
+		post.ScheduledTime = req.ScheduledTime.UTC()
+		post.Timezone = req.Timezone
+	}
+	if req.LocalScheduledTime != "" {
+		scheduledTime, timezone, err := h.resolveScheduledTime(c.GetString("user_id"), req.LocalScheduledTime, req.Timezone, req.DST)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled time: " + err.Error()})
+			return
+		}
+		post.ScheduledTime = scheduledTime
+		post.Timezone = timezone
+	}
+
+	if post.SeriesID != "" {
+		// Editing a single occurrence detaches it from later changes to the series
+		post.Detached = true
+	}
+	if post.Recurrence != nil && req.Status == models.PostStatusScheduled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Recurring series templates cannot be scheduled directly"})
+		return
+	}
+
+	if req.Status != "" && req.Status != post.Status {
+		if err := post.TransitionTo(req.Status); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if post.Status == models.PostStatusScheduled && post.ScheduledTime.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scheduled time is required for scheduled posts"})
+		return
+	}
+	if err := post.ValidateForPlatforms(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.db.UpdatePost(post, c.GetString("user_id"))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		if current, err := h.db.GetPost(postID); err == nil {
+			h.preconditionFailed(c, current)
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		return
+	}
+	if post.Recurrence != nil && post.Recurrence.EndedAt == nil {
+		if err := h.updateFutureOccurrences(post, c.GetString("user_id")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Post updated but its upcoming occurrences could not be updated"})
+			return
+		}
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post updated successfully",
+		"post":    h.localizePost(c, post),
+	})
+}
+
+// preconditionFailed rejects a stale write and hands the client the current version
+func (h *Handler) preconditionFailed(c *gin.Context, current *models.Post) {
+	c.Header("ETag", postETag(current))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": "Post has been modified since it was last read",
+		"post":  current,
+	})
 }
 
 func (h *Handler) DeletePost(c *gin.Context) {
@@ -181,10 +404,122 @@ func (h *Handler) DeletePost(c *gin.Context) {
 		return
 	}
 
-	if err := h.db.DeletePost(postID); err != nil {
+	post, ok := h.getOwnedPost(c, postID)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeletePost(post, c.GetString("user_id")); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Post moved to trash"})
+}
+
+// getOwnedPost loads a post and makes sure it belongs to the authenticated user.
+// Trashed posts are reported as not found.
+// It writes the error response itself and reports whether the caller may continue.
+func (h *Handler) getOwnedPost(c *gin.Context, postID string) (*models.Post, bool) {
+	return h.findOwnedPost(c, postID, false)
+}
+
+// findOwnedPost is getOwnedPost that looks either at live or at trashed posts
+func (h *Handler) findOwnedPost(c *gin.Context, postID string, trashed bool) (*models.Post, bool) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	post, err := h.db.GetPost(postID)
+	if errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post"})
+		return nil, false
+	}
+	if post.UserID != userID || (post.DeletedAt != nil) != trashed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+
+	return post, true
+}
+
+// saveUpload adds a file whose content is an accepted media format to the
+// user's media library. Files are stored under the hash of their content,
+// images without their metadata: a file the user uploaded before returns the
+// existing asset, with created false. The client supplied content type is
+// ignored.
+func (h *Handler) saveUpload(c *gin.Context, userID string, file *multipart.FileHeader) (asset *models.MediaAsset, created bool, err error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open file: %v", err)
+	}
+	defer src.Close()
+
+	header := make([]byte, media.SniffLen)
+	n, err := io.ReadFull(src, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, false, fmt.Errorf("failed to read file: %v", err)
+	}
+	header = header[:n]
+	format, err := media.Check(header, file.Size)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", file.Filename, err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(src, 0, file.Size)); err != nil {
+		return nil, false, fmt.Errorf("failed to read file: %v", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	existing, err := h.db.GetUserMediaByHash(userID, sum)
+	if err == nil {
+		return existing, false, nil
+	}
+	if !errors.Is(err, repository.ErrMediaNotFound) {
+		return nil, false, err
+	}
+
+	asset = &models.MediaAsset{
+		UserID:      userID,
+		Key:         contentKey(userID, sum, format),
+		Type:        format.Type,
+		FileName:    media.SanitizeFileName(file.Filename, format),
+		ContentType: format.ContentType,
+		Size:        file.Size,
+		SHA256:      sum,
+	}
+	if format.Type == "video" {
+		info, err := media.ParseVideo(io.NewSectionReader(src, 0, file.Size), file.Size)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+		asset.Width, asset.Height, asset.Video = info.Width, info.Height, videoMetadata(info)
+	}
+
+	var body io.Reader = io.MultiReader(bytes.NewReader(header), src)
+	if format.Type == "image" {
+		data, err := cleanImage(io.NewSectionReader(src, 0, file.Size), format)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+		body, asset.Size = bytes.NewReader(data), int64(len(data))
+	}
+	if err := h.storage.Put(c.Request.Context(), asset.Key, body, asset.Size, format.ContentType); err != nil {
+		return nil, false, fmt.Errorf("failed to save file: %v", err)
+	}
+	asset.URL = h.storage.URL(asset.Key)
+	if err := h.db.CreateMedia(asset); err != nil {
+		h.removeMediaFiles(context.Background(), asset)
+		if errors.Is(err, models.ErrStorageQuotaExceeded) {
+			return nil, false, fmt.Errorf("%s: %w", file.Filename, err)
+		}
+		return nil, false, err
+	}
+	return asset, true, nil
 }
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/utils"
+)
+
+func (h *Handler) GetPreferences(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	prefs, err := h.db.GetUserPreferences(userID)
+	if errors.Is(err, repository.ErrPreferencesNotFound) {
+		prefs = &models.UserPreferences{UserID: userID, Timezone: "UTC"}
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+		return
+	}
+
+	prefs := &models.UserPreferences{
+		UserID:   userID,
+		Timezone: req.Timezone,
+	}
+	if err := h.db.SaveUserPreferences(prefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Preferences saved successfully",
+		"preferences": prefs,
+	})
+}
+
+// userLocation returns the preferred timezone of a user, falling back to UTC
+func (h *Handler) userLocation(userID string) *time.Location {
+	prefs, err := h.db.GetUserPreferences(userID)
+	if err != nil {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// resolveScheduledTime turns a client supplied schedule into a UTC instant.
+// RFC3339 values carry their own offset; anything else is a wall-clock time
+// read in zone, or in the user's preferred timezone when zone is empty.
+// It also returns the name of the zone the time was entered in.
+func (h *Handler) resolveScheduledTime(userID, value, zone, dst string) (time.Time, string, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), zone, validTimezone(zone)
+	}
+	return resolveScheduledTimeIn(h.userLocation(userID), value, zone, dst)
+}
+
+// resolveScheduledTimeIn is resolveScheduledTime with the user's preferred
+// timezone already looked up, for resolving many schedules at once
+func resolveScheduledTimeIn(loc *time.Location, value, zone, dst string) (time.Time, string, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), zone, validTimezone(zone)
+	}
+
+	if !utils.ValidDSTPolicy(dst) {
+		return time.Time{}, "", errors.New("invalid dst policy")
+	}
+
+	if zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return time.Time{}, "", errors.New("invalid timezone")
+		}
+	}
+
+	t, err := utils.ParseLocalTime(value, loc, dst)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return t.UTC(), loc.String(), nil
+}
+
+// localizePosts renders post timestamps in the preferred timezone of the authenticated user
+func (h *Handler) localizePosts(c *gin.Context, posts []models.Post) []models.Post {
+	loc := h.userLocation(c.GetString("user_id"))
+	localized := make([]models.Post, len(posts))
+	for i, post := range posts {
+		localized[i] = post.InLocation(loc)
+	}
+	return localized
+}
+
+func (h *Handler) localizePost(c *gin.Context, post *models.Post) models.Post {
+	return post.InLocation(h.userLocation(c.GetString("user_id")))
+}
+
+// validTimezone checks a zone recorded with a schedule; empty means unspecified
+func validTimezone(zone string) error {
+	if zone == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(zone); err != nil {
+		return errors.New("invalid timezone")
+	}
+	return nil
+}
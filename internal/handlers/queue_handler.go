@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+func (h *Handler) GetQueue(c *gin.Context) {
+	queue, ok := h.getOwnedQueue(c)
+	if !ok {
+		return
+	}
+
+	posts := make([]models.Post, 0, len(queue.PostIDs))
+	for _, id := range queue.PostIDs {
+		post, err := h.db.GetPost(id)
+		if errors.Is(err, repository.ErrPostNotFound) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queued posts"})
+			return
+		}
+		posts = append(posts, *post)
+	}
+
+	c.Header("ETag", queueETag(queue))
+	c.JSON(http.StatusOK, gin.H{"queue": queue, "posts": posts})
+}
+
+// SetQueueSchedule creates the queue of a social account or replaces its
+// weekly slots, moving every queued post to the recalculated slots
+func (h *Handler) SetQueueSchedule(c *gin.Context) {
+	var req models.QueueScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	accountID := c.Param("account_id")
+	if !h.checkOwnedAccount(c, accountID) {
+		return
+	}
+
+	queue, err := h.db.GetQueue(accountID)
+	switch {
+	case errors.Is(err, repository.ErrQueueNotFound):
+		queue = &models.PostingQueue{AccountID: accountID, UserID: userID}
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queue"})
+		return
+	case queue.UserID != userID:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Queue not found"})
+		return
+	case !h.checkQueueVersion(c, queue):
+		return
+	}
+
+	queue.Timezone = req.Timezone
+	queue.Slots = req.Slots
+	if err := queue.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.respondRecalculated(c, queue, "Queue schedule saved successfully")
+}
+
+// AddToQueue puts a post at the end of the queue, giving it the next free slot
+func (h *Handler) AddToQueue(c *gin.Context) {
+	var req models.QueuePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	queue, ok := h.getQueueForUpdate(c)
+	if !ok {
+		return
+	}
+
+	post, ok := h.getOwnedPost(c, req.PostID)
+	if !ok {
+		return
+	}
+	if queue.Contains(post.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post is already queued"})
+		return
+	}
+	if post.AccountID != "" && post.AccountID != queue.AccountID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Post belongs to another account"})
+		return
+	}
+	if post.Recurrence != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Recurring series templates cannot be queued"})
+		return
+	}
+	if post.Status != models.PostStatusDraft && post.Status != models.PostStatusScheduled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft or scheduled posts can be queued"})
+		return
+	}
+
+	queue.PostIDs = append(queue.PostIDs, post.ID)
+	h.respondRecalculated(c, queue, "Post added to queue")
+}
+
+// RemoveFromQueue takes a post out of the queue and moves it back to draft
+func (h *Handler) RemoveFromQueue(c *gin.Context) {
+	queue, ok := h.getQueueForUpdate(c)
+	if !ok {
+		return
+	}
+
+	postID := c.Param("post_id")
+	if !queue.Contains(postID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post is not queued"})
+		return
+	}
+	queue.Remove(postID)
+	posts, ok := h.saveRecalculated(c, queue)
+	if !ok {
+		return
+	}
+
+	post, err := h.db.GetPost(postID)
+	if err != nil && !errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get post"})
+		return
+	}
+	if post != nil {
+		post.AccountID = ""
+		if post.Status == models.PostStatusScheduled {
+			if err := post.TransitionTo(models.PostStatusDraft); err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if err := h.db.UpdatePost(post, c.GetString("user_id")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+			return
+		}
+	}
+
+	h.respondQueue(c, queue, posts, "Post removed from queue")
+}
+
+// ReorderQueue sets a new order for the queued posts; post_ids must contain every queued post
+func (h *Handler) ReorderQueue(c *gin.Context) {
+	var req models.QueueOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	queue, ok := h.getQueueForUpdate(c)
+	if !ok {
+		return
+	}
+
+	if len(req.PostIDs) != len(queue.PostIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "post_ids must list every queued post exactly once"})
+		return
+	}
+	seen := make(map[string]bool)
+	for _, id := range req.PostIDs {
+		if seen[id] || !queue.Contains(id) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "post_ids must list every queued post exactly once"})
+			return
+		}
+		seen[id] = true
+	}
+
+	queue.PostIDs = req.PostIDs
+	h.respondRecalculated(c, queue, "Queue reordered successfully")
+}
+
+func (h *Handler) ShuffleQueue(c *gin.Context) {
+	queue, ok := h.getQueueForUpdate(c)
+	if !ok {
+		return
+	}
+
+	rand.Shuffle(len(queue.PostIDs), func(i, j int) {
+		queue.PostIDs[i], queue.PostIDs[j] = queue.PostIDs[j], queue.PostIDs[i]
+	})
+	h.respondRecalculated(c, queue, "Queue shuffled successfully")
+}
+
+// PauseQueue stops publishing from the queue; queued posts go back to draft until it is resumed
+func (h *Handler) PauseQueue(c *gin.Context) {
+	h.setQueuePaused(c, true)
+}
+
+func (h *Handler) ResumeQueue(c *gin.Context) {
+	h.setQueuePaused(c, false)
+}
+
+func (h *Handler) setQueuePaused(c *gin.Context, paused bool) {
+	queue, ok := h.getQueueForUpdate(c)
+	if !ok {
+		return
+	}
+
+	queue.Paused = paused
+	message := "Queue resumed"
+	if paused {
+		message = "Queue paused"
+	}
+	h.respondRecalculated(c, queue, message)
+}
+
+func (h *Handler) getOwnedQueue(c *gin.Context) (*models.PostingQueue, bool) {
+	if !h.checkOwnedAccount(c, c.Param("account_id")) {
+		return nil, false
+	}
+
+	queue, err := h.db.GetQueue(c.Param("account_id"))
+	if errors.Is(err, repository.ErrQueueNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Queue not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get queue"})
+		return nil, false
+	}
+	if queue.UserID != c.GetString("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Queue not found"})
+		return nil, false
+	}
+	return queue, true
+}
+
+// getQueueForUpdate is getOwnedQueue for changes, which have to name the
+// version of the queue they were made to in If-Match
+func (h *Handler) getQueueForUpdate(c *gin.Context) (*models.PostingQueue, bool) {
+	queue, ok := h.getOwnedQueue(c)
+	if !ok || !h.checkQueueVersion(c, queue) {
+		return nil, false
+	}
+	return queue, true
+}
+
+// checkQueueVersion rejects a change unless If-Match names the stored version of the queue
+func (h *Handler) checkQueueVersion(c *gin.Context, queue *models.PostingQueue) bool {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return false
+	}
+	seqNo, primaryTerm, err := parseETag(ifMatch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid If-Match header"})
+		return false
+	}
+	if queue.SeqNo != seqNo || queue.PrimaryTerm != primaryTerm {
+		h.queuePreconditionFailed(c, queue)
+		return false
+	}
+	return true
+}
+
+// queuePreconditionFailed rejects a stale change and hands the client the current queue
+func (h *Handler) queuePreconditionFailed(c *gin.Context, current *models.PostingQueue) {
+	c.Header("ETag", queueETag(current))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": "Queue has been modified since it was last read",
+		"queue": current,
+	})
+}
+
+// checkOwnedAccount responds 404 unless the social account belongs to the user
+func (h *Handler) checkOwnedAccount(c *gin.Context, accountID string) bool {
+	owned, err := h.db.AccountOwnedBy(accountID, c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get account"})
+		return false
+	}
+	if !owned {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return false
+	}
+	return true
+}
+
+func (h *Handler) respondRecalculated(c *gin.Context, queue *models.PostingQueue, message string) {
+	posts, ok := h.saveRecalculated(c, queue)
+	if !ok {
+		return
+	}
+	h.respondQueue(c, queue, posts, message)
+}
+
+func (h *Handler) respondQueue(c *gin.Context, queue *models.PostingQueue, posts []models.Post, message string) {
+	c.Header("ETag", queueETag(queue))
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"queue":   queue,
+		"posts":   posts,
+	})
+}
+
+// saveRecalculated runs recalculateQueue, responding with an error when it fails
+func (h *Handler) saveRecalculated(c *gin.Context, queue *models.PostingQueue) ([]models.Post, bool) {
+	posts, err := h.recalculateQueue(queue, c.GetString("user_id"))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		current, err := h.db.GetQueue(queue.AccountID)
+		if err == nil {
+			h.queuePreconditionFailed(c, current)
+			return nil, false
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "Queue has been modified since it was last read"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recalculate queue"})
+		return nil, false
+	}
+	return posts, true
+}
+
+// recalculateQueue assigns the queued posts, in order, to the next free slots
+// and saves the queue. Slots taken by other scheduled posts of the account are
+// left free. Posts that were deleted or published meanwhile are dropped. While
+// the queue is paused its posts are kept as drafts instead. The queue is saved
+// before its posts, so a conflicting change leaves them untouched.
+func (h *Handler) recalculateQueue(queue *models.PostingQueue, userID string) ([]models.Post, error) {
+	var posts []*models.Post
+	var ids []string
+	for _, id := range queue.PostIDs {
+		post, err := h.db.GetPost(id)
+		if errors.Is(err, repository.ErrPostNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if post.DeletedAt != nil || post.UserID != queue.UserID ||
+			(post.Status != models.PostStatusDraft && post.Status != models.PostStatusScheduled) {
+			continue
+		}
+		posts = append(posts, post)
+		ids = append(ids, id)
+	}
+	queue.PostIDs = ids
+
+	var slots []time.Time
+	if !queue.Paused {
+		now := time.Now()
+		occupied, err := h.db.GetAccountScheduledTimes(queue.AccountID, now, ids)
+		if err != nil {
+			return nil, err
+		}
+		if slots, err = queue.NextSlots(now, len(posts), occupied); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.db.SaveQueue(queue); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Post, len(posts))
+	for i, post := range posts {
+		status := models.PostStatusDraft
+		scheduledTime := post.ScheduledTime
+		if !queue.Paused {
+			status = models.PostStatusScheduled
+			scheduledTime = slots[i]
+		}
+
+		if post.Status != status || !post.ScheduledTime.Equal(scheduledTime) || post.AccountID != queue.AccountID {
+			post.AccountID = queue.AccountID
+			post.ScheduledTime = scheduledTime
+			if post.Status != status {
+				if err := post.TransitionTo(status); err != nil {
+					return nil, err
+				}
+			}
+			// A conflict here is on the post, not on the queue
+			if err := h.db.UpdatePost(post, userID); err != nil {
+				return nil, fmt.Errorf("error updating queued post %s: %v", post.ID, err)
+			}
+		}
+		result[i] = *post
+	}
+	return result, nil
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+// SetRecurrence turns a draft post into the template of a recurring series,
+// or replaces the schedule of an existing series
+func (h *Handler) SetRecurrence(c *gin.Context) {
+	var req models.RecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if post.SeriesID != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An occurrence of a series cannot be recurring itself"})
+		return
+	}
+	if post.Status != models.PostStatusDraft {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only draft posts can become recurring series"})
+		return
+	}
+
+	recurrence := &models.Recurrence{
+		RRule:    req.RRule,
+		Timezone: req.Timezone,
+		Start:    req.Start,
+	}
+	if recurrence.Start.IsZero() {
+		recurrence.Start = post.ScheduledTime
+	}
+	if err := recurrence.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if post.Recurrence != nil {
+		if err := h.cancelFutureOccurrences(post, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel upcoming occurrences"})
+			return
+		}
+	}
+	post.Recurrence = recurrence
+
+	if !h.saveSeries(c, post) {
+		return
+	}
+
+	now := time.Now()
+	upcoming, _ := recurrence.Between(now, now.Add(h.cfg.RecurrenceHorizon))
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Recurrence saved successfully",
+		"post":     post,
+		"upcoming": upcoming,
+	})
+}
+
+// EndRecurrence ends a series; upcoming occurrences that were not edited on their own are trashed
+func (h *Handler) EndRecurrence(c *gin.Context) {
+	post, ok := h.getSeries(c)
+	if !ok {
+		return
+	}
+
+	if err := h.cancelFutureOccurrences(post, c.GetString("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel upcoming occurrences"})
+		return
+	}
+	now := time.Now()
+	post.Recurrence.EndedAt = &now
+
+	if !h.saveSeries(c, post) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Series ended successfully",
+		"post":    post,
+	})
+}
+
+// GetOccurrences lists the upcoming occurrence times of a series until ?until=
+// along with the posts that have already been materialized
+func (h *Handler) GetOccurrences(c *gin.Context) {
+	post, ok := h.getSeries(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	until := now.Add(h.cfg.RecurrenceHorizon)
+	if s := c.Query("until"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until format"})
+			return
+		}
+		until = t
+	}
+
+	occurrences, err := post.Recurrence.Between(now, until)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	posts, err := h.db.GetSeriesOccurrences(post.ID, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get occurrences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"occurrences": occurrences,
+		"posts":       posts,
+	})
+}
+
+// SkipOccurrence excludes a single occurrence from a series, trashing its post if it was already materialized
+func (h *Handler) SkipOccurrence(c *gin.Context) {
+	var req models.SkipOccurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	post, ok := h.getSeries(c)
+	if !ok {
+		return
+	}
+
+	at := req.OccurrenceTime.UTC()
+	occurs, err := post.Recurrence.Occurs(at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !occurs {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No occurrence of the series at the given time"})
+		return
+	}
+	if post.Recurrence.IsSkipped(at) {
+		c.JSON(http.StatusOK, gin.H{"message": "Occurrence already skipped", "post": post})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	occurrence, err := h.db.GetSeriesOccurrence(post.ID, at)
+	if err != nil && !errors.Is(err, repository.ErrPostNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get occurrence"})
+		return
+	}
+	if occurrence != nil {
+		if err := h.db.DeletePost(occurrence, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove occurrence"})
+			return
+		}
+	}
+
+	post.Recurrence.Exceptions = append(post.Recurrence.Exceptions, at)
+	if !h.saveSeries(c, post) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Occurrence skipped successfully",
+		"post":    post,
+	})
+}
+
+// getSeries loads an owned post and makes sure it is an active recurring series
+func (h *Handler) getSeries(c *gin.Context) (*models.Post, bool) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return nil, false
+	}
+	if post.Recurrence == nil || post.Recurrence.EndedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post is not an active recurring series"})
+		return nil, false
+	}
+	return post, true
+}
+
+// saveSeries stores a series template, answering with 412 when it was modified concurrently
+func (h *Handler) saveSeries(c *gin.Context, post *models.Post) bool {
+	err := h.db.UpdatePost(post, c.GetString("user_id"))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		if current, err := h.db.GetPost(post.ID); err == nil {
+			h.preconditionFailed(c, current)
+			return false
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return false
+	}
+	c.Header("ETag", postETag(post))
+	return true
+}
+
+// cancelFutureOccurrences trashes the upcoming occurrences of a series that
+// have not been edited on their own or picked up for publishing yet. The
+// materializer starts over from now; slots whose occurrence was kept are not
+// filled again.
+func (h *Handler) cancelFutureOccurrences(series *models.Post, userID string) error {
+	occurrences, err := h.db.GetSeriesOccurrences(series.ID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range occurrences {
+		occurrence := &occurrences[i]
+		if occurrence.Detached || occurrence.Status != models.PostStatusScheduled {
+			continue
+		}
+		if err := h.db.DeletePost(occurrence, userID); err != nil {
+			return err
+		}
+	}
+
+	series.Recurrence.MaterializedUntil = nil
+	return nil
+}
+
+// updateFutureOccurrences carries changes of a series template over to its
+// upcoming occurrences that have not been edited on their own or picked up
+// for publishing yet
+func (h *Handler) updateFutureOccurrences(series *models.Post, userID string) error {
+	occurrences, err := h.db.GetSeriesOccurrences(series.ID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range occurrences {
+		occurrence := &occurrences[i]
+		if occurrence.Detached || occurrence.Status != models.PostStatusScheduled {
+			continue
+		}
+		series.ApplyToOccurrence(occurrence)
+		err := h.db.UpdatePost(occurrence, userID)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			// Edited or claimed for publishing concurrently
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,375 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/media"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
+)
+
+// Resumable uploads follow the core protocol of tus 1.0.0 with the creation,
+// expiration and termination extensions
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+	// maxResumableUploadSize is the largest file of any media type
+	maxResumableUploadSize = media.MaxVideoSize
+	// resumableUploadExpiry is how long an upload is kept without receiving data
+	resumableUploadExpiry = 24 * time.Hour
+)
+
+// CreateResumableUpload starts a resumable upload of Upload-Length bytes. The
+// file name and type are taken from the filename and filetype metadata.
+func (h *Handler) CreateResumableUpload(c *gin.Context) {
+	setTusHeaders(c)
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !checkTusVersion(c) {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Length must be a positive integer"})
+		return
+	}
+	if length > maxResumableUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Upload is too large"})
+		return
+	}
+	metadata, err := models.ParseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fileName := metadata["filename"]
+	if fileName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filename metadata is required"})
+		return
+	}
+	contentType := metadata["filetype"]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(fileName))
+	}
+	format, ok := media.FormatOf(contentType)
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file type " + contentType})
+		return
+	}
+	if err := media.CheckSize(format.Type, length); err != nil {
+		uploadError(c, err)
+		return
+	}
+
+	fileName = media.SanitizeFileName(fileName, format)
+	upload := models.MediaUpload{
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: format.ContentType,
+		Size:        length,
+		Resumable:   true,
+		ExpiresAt:   time.Now().Add(resumableUploadExpiry),
+	}
+	if err := h.db.CreateMediaUpload(&upload); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+	if err := h.staging.Create(upload.ID); err != nil {
+		h.db.DeleteMediaUpload(upload.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, upload.ID))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// GetResumableUploadOffset reports how much of an upload has been received
+func (h *Handler) GetResumableUploadOffset(c *gin.Context) {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return
+	}
+	upload, ok := h.getResumableUpload(c)
+	if !ok {
+		return
+	}
+
+	offset, err := h.staging.Offset(upload.ID)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.Status(http.StatusGone)
+		return
+	}
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// PatchResumableUpload appends a chunk at Upload-Offset. The chunk completing
+// the upload moves the file to storage and adds it to the media library; its
+// ID is returned in Upload-Media-Id. A failed assembly is retried by sending
+// an empty chunk at the final offset.
+func (h *Handler) PatchResumableUpload(c *gin.Context) {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upload-Offset must be a non-negative integer"})
+		return
+	}
+
+	upload, ok := h.getResumableUpload(c)
+	if !ok {
+		return
+	}
+	if err := h.staging.Lock(upload.ID); err != nil {
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+		return
+	}
+	defer h.staging.Unlock(upload.ID)
+
+	offset, err = h.staging.Append(upload.ID, offset, c.Request.Body, upload.Size)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusGone, gin.H{"error": "Upload has expired"})
+		return
+	case errors.Is(err, storage.ErrOffsetMismatch):
+		c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, storage.ErrUploadTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		// The data received before the connection failed is kept
+		log.Printf("Failed to receive chunk of upload %s: %v", upload.ID, err)
+		c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	if offset < upload.Size {
+		upload.ExpiresAt = time.Now().Add(resumableUploadExpiry)
+		if err := h.db.UpdateMediaUpload(upload); err != nil {
+			log.Printf("Failed to extend upload %s: %v", upload.ID, err)
+		}
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	asset, err := h.assembleResumableUpload(c, upload)
+	if errors.Is(err, media.ErrUnsupported) {
+		// The content will not change by retrying, the upload is discarded
+		h.staging.Remove(upload.ID)
+		h.db.DeleteMediaUpload(upload.ID)
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrStorageQuotaExceeded) {
+		// The upload is kept so it can be completed once space is freed
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to assemble upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		return
+	}
+	c.Header("Upload-Media-Id", asset.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteResumableUpload abandons an upload and discards the data received
+func (h *Handler) DeleteResumableUpload(c *gin.Context) {
+	setTusHeaders(c)
+	if !checkTusVersion(c) {
+		return
+	}
+	upload, ok := h.getResumableUpload(c)
+	if !ok {
+		return
+	}
+	if err := h.staging.Lock(upload.ID); err != nil {
+		c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
+		return
+	}
+	defer h.staging.Unlock(upload.ID)
+
+	if err := h.staging.Remove(upload.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete upload"})
+		return
+	}
+	if err := h.db.DeleteMediaUpload(upload.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete upload"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// assembleResumableUpload checks that a complete upload has the declared
+// format, reads the metadata of videos, stores it under the hash of its
+// content, images without their metadata, and adds it to the media library.
+// A file the user uploaded before resolves to its existing asset.
+func (h *Handler) assembleResumableUpload(c *gin.Context, upload *models.MediaUpload) (*models.MediaAsset, error) {
+	file, err := h.staging.Open(upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, media.SniffLen)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	format, err := media.Sniff(header[:n])
+	if err != nil {
+		return nil, err
+	}
+	if format.ContentType != upload.ContentType {
+		return nil, fmt.Errorf("%w: the file is %s, not the declared %s", media.ErrUnsupported, format.ContentType, upload.ContentType)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, upload.Size)); err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	asset, err := h.db.GetUserMediaByHash(upload.UserID, sum)
+	if err == nil {
+		h.finishResumableUpload(upload)
+		return asset, nil
+	}
+	if !errors.Is(err, repository.ErrMediaNotFound) {
+		return nil, err
+	}
+
+	var video *media.VideoInfo
+	if format.Type == "video" {
+		if video, err = media.ParseVideo(io.NewSectionReader(file, 0, upload.Size), upload.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	var body io.Reader = io.NewSectionReader(file, 0, upload.Size)
+	size := upload.Size
+	if format.Type == "image" {
+		data, err := cleanImage(body, format)
+		if err != nil {
+			return nil, err
+		}
+		body, size = bytes.NewReader(data), int64(len(data))
+	}
+
+	key := contentKey(upload.UserID, sum, format)
+	if err := h.storage.Put(c.Request.Context(), key, body, size, upload.ContentType); err != nil {
+		return nil, err
+	}
+
+	asset = &models.MediaAsset{
+		UserID:      upload.UserID,
+		Key:         key,
+		URL:         h.storage.URL(key),
+		Type:        format.Type,
+		FileName:    upload.FileName,
+		ContentType: upload.ContentType,
+		Size:        size,
+		SHA256:      sum,
+	}
+	if video != nil {
+		asset.Width, asset.Height, asset.Video = video.Width, video.Height, videoMetadata(video)
+	}
+	if err := h.db.CreateMedia(asset); err != nil {
+		if err := h.removeUnusedFile(c.Request.Context(), key); err != nil {
+			log.Printf("Failed to remove unsaved upload %s: %v", key, err)
+		}
+		return nil, err
+	}
+	h.finishResumableUpload(upload)
+	return asset, nil
+}
+
+// finishResumableUpload deletes a completed upload and its staged data
+func (h *Handler) finishResumableUpload(upload *models.MediaUpload) {
+	if err := h.db.DeleteMediaUpload(upload.ID); err != nil {
+		log.Printf("Failed to delete completed upload %s: %v", upload.ID, err)
+	}
+	if err := h.staging.Remove(upload.ID); err != nil {
+		log.Printf("Failed to remove staged upload %s: %v", upload.ID, err)
+	}
+}
+
+// getResumableUpload loads the upload in the path if it belongs to the user
+// and has not expired
+func (h *Handler) getResumableUpload(c *gin.Context) (*models.MediaUpload, bool) {
+	upload, err := h.db.GetMediaUpload(c.Param("id"))
+	if errors.Is(err, repository.ErrMediaUploadNotFound) ||
+		(err == nil && (upload.UserID != c.GetString("user_id") || !upload.Resumable)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upload"})
+		return nil, false
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "Upload has expired"})
+		return nil, false
+	}
+	return upload, true
+}
+
+func setTusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(maxResumableUploadSize, 10))
+}
+
+// checkTusVersion rejects requests that do not state our protocol version,
+// which tus requires of every request but OPTIONS
+func checkTusVersion(c *gin.Context) bool {
+	version := c.GetHeader("Tus-Resumable")
+	if version == "" {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Tus-Resumable header is required"})
+		return false
+	}
+	if version != tusVersion {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Unsupported tus version " + version})
+		return false
+	}
+	return true
+}
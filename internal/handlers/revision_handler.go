@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+func (h *Handler) GetPostRevisions(c *gin.Context) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	revisions, err := h.db.GetPostRevisions(post.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// GetPostRevisionDiff compares two versions of a post given as ?from= and ?to=.
+// When to is omitted the current version of the post is used.
+func (h *Handler) GetPostRevisionDiff(c *gin.Context) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	fromVersion, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from version"})
+		return
+	}
+	toVersion := post.Version
+	if to := c.Query("to"); to != "" {
+		if toVersion, err = strconv.Atoi(to); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to version"})
+			return
+		}
+	}
+
+	from, ok := h.getRevision(c, post.ID, fromVersion)
+	if !ok {
+		return
+	}
+	to, ok := h.getRevision(c, post.ID, toVersion)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from.Version,
+		"to":      to.Version,
+		"changes": models.DiffPosts(&from.Snapshot, &to.Snapshot),
+	})
+}
+
+// RestorePostRevision copies the content of an earlier version back onto the post.
+// The restore itself is recorded as a new revision.
+func (h *Handler) RestorePostRevision(c *gin.Context) {
+	post, ok := h.getOwnedPost(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	rev, ok := h.getRevision(c, post.ID, version)
+	if !ok {
+		return
+	}
+
+	if post.Status == models.PostStatusPublishing || post.Status == models.PostStatusPublished {
+		c.JSON(http.StatusConflict, gin.H{"error": "Published posts cannot be edited"})
+		return
+	}
+
+	post.RestoreRevision(rev)
+	if !h.checkMediaExists(c, post) {
+		return
+	}
+	if err := post.ValidateForPlatforms(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err = h.db.UpdatePost(post, c.GetString("user_id"))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		if current, err := h.db.GetPost(post.ID); err == nil {
+			h.preconditionFailed(c, current)
+			return
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Revision restored successfully",
+		"post":    post,
+	})
+}
+
+func (h *Handler) getRevision(c *gin.Context, postID string, version int) (*models.PostRevision, bool) {
+	rev, err := h.db.GetPostRevision(postID, version)
+	if errors.Is(err, repository.ErrRevisionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get revision"})
+		return nil, false
+	}
+	return rev, true
+}
+
+// checkMediaExists rejects restoring a revision whose library media has since
+// been deleted
+func (h *Handler) checkMediaExists(c *gin.Context, post *models.Post) bool {
+	ids := post.MediaIDs()
+	if len(ids) == 0 {
+		return true
+	}
+	assets, err := h.db.GetMediaByIDs(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get media"})
+		return false
+	}
+	var missing []string
+	for _, id := range ids {
+		if _, ok := assets[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Media of this revision has been deleted: " + strings.Join(missing, ", ")})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// storageUsageResponse is a usage record with the quota that applies to it
+type storageUsageResponse struct {
+	*models.StorageUsage
+	Limits models.StorageQuota `json:"limits"`
+}
+
+func newStorageUsageResponse(usage *models.StorageUsage) storageUsageResponse {
+	return storageUsageResponse{StorageUsage: usage, Limits: usage.Limits()}
+}
+
+// GetStorageUsage reports how much of their storage quota the user uses
+func (h *Handler) GetStorageUsage(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	usage, err := h.db.GetStorageUsage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage usage"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"storage": newStorageUsageResponse(usage)})
+}
+
+// ListStorageUsage lists the storage usage of users, largest first
+func (h *Handler) ListStorageUsage(c *gin.Context) {
+	usages, err := h.db.ListStorageUsage(1000)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage usage"})
+		return
+	}
+
+	storage := make([]storageUsageResponse, len(usages))
+	for i := range usages {
+		storage[i] = newStorageUsageResponse(&usages[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"storage": storage})
+}
+
+// GetUserStorageUsage reports the storage usage and quota of a user
+func (h *Handler) GetUserStorageUsage(c *gin.Context) {
+	usage, err := h.db.GetStorageUsage(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage usage"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"storage": newStorageUsageResponse(usage)})
+}
+
+// UpdateUserStorageQuota moves a user to another plan, optionally with a
+// quota of their own
+func (h *Handler) UpdateUserStorageQuota(c *gin.Context) {
+	var req models.UpdateStorageQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := models.GetStorageQuota(req.Plan); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown plan " + req.Plan})
+		return
+	}
+
+	usage, err := h.db.UpdateStorageQuota(c.Param("id"), req.Plan, req.Quota)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update storage quota"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Storage quota updated successfully",
+		"storage": newStorageUsageResponse(usage),
+	})
+}
+
+// RecalculateUserStorageUsage recounts the usage of a user from their media
+// library, correcting any drift of the incremental counters
+func (h *Handler) RecalculateUserStorageUsage(c *gin.Context) {
+	usage, err := h.db.RecalculateStorageUsage(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recalculate storage usage"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"storage": newStorageUsageResponse(usage)})
+}
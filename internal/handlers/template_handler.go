@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+func (h *Handler) CreateTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := &models.PostTemplate{UserID: userID}
+	applyTemplateRequest(template, &req)
+	if err := h.db.CreateTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Template created successfully",
+		"template": template,
+	})
+}
+
+func (h *Handler) GetTemplates(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	templates, err := h.db.GetUserTemplates(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+func (h *Handler) GetTemplate(c *gin.Context) {
+	template, ok := h.getOwnedTemplate(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"template": template})
+}
+
+func (h *Handler) UpdateTemplate(c *gin.Context) {
+	var req models.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, ok := h.getOwnedTemplate(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	applyTemplateRequest(template, &req)
+	if err := h.db.UpdateTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Template updated successfully",
+		"template": template,
+	})
+}
+
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	template, ok := h.getOwnedTemplate(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteTemplate(template); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// RenderTemplate fills in the snippets and variables of a template and creates a
+// draft post from it. With ?dry_run=true the rendered post is returned without saving.
+func (h *Handler) RenderTemplate(c *gin.Context) {
+	var req models.RenderTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, ok := h.getOwnedTemplate(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	snippets, err := h.db.GetUserSnippets(template.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get snippets"})
+		return
+	}
+	contents := make(map[string]string, len(snippets))
+	for _, snippet := range snippets {
+		contents[snippet.Name] = snippet.Content
+	}
+
+	post, err := template.Render(req.Variables, contents)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	post.UserID = template.UserID
+	if len(req.Platforms) > 0 {
+		post.Platforms = req.Platforms
+	}
+	if err := post.ValidateForPlatforms(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		c.JSON(http.StatusOK, gin.H{"post": h.localizePost(c, post)})
+		return
+	}
+
+	if err := h.db.CreatePost(post); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Post created from template",
+		"post":    h.localizePost(c, post),
+	})
+}
+
+func (h *Handler) CreateSnippet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.SnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.validSnippetName(c, userID, req.Name, "") {
+		return
+	}
+
+	snippet := &models.Snippet{
+		UserID:  userID,
+		Name:    req.Name,
+		Content: req.Content,
+	}
+	if err := h.db.CreateSnippet(snippet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create snippet"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Snippet created successfully",
+		"snippet": snippet,
+	})
+}
+
+func (h *Handler) GetSnippets(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	snippets, err := h.db.GetUserSnippets(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get snippets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snippets": snippets})
+}
+
+func (h *Handler) GetSnippet(c *gin.Context) {
+	snippet, ok := h.getOwnedSnippet(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snippet": snippet})
+}
+
+func (h *Handler) UpdateSnippet(c *gin.Context) {
+	var req models.SnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	snippet, ok := h.getOwnedSnippet(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if !h.validSnippetName(c, snippet.UserID, req.Name, snippet.ID) {
+		return
+	}
+
+	snippet.Name = req.Name
+	snippet.Content = req.Content
+	if err := h.db.UpdateSnippet(snippet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update snippet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Snippet updated successfully",
+		"snippet": snippet,
+	})
+}
+
+func (h *Handler) DeleteSnippet(c *gin.Context) {
+	snippet, ok := h.getOwnedSnippet(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteSnippet(snippet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete snippet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snippet deleted successfully"})
+}
+
+func applyTemplateRequest(template *models.PostTemplate, req *models.TemplateRequest) {
+	template.Name = req.Name
+	template.Title = req.Title
+	template.Content = req.Content
+	template.Platforms = req.Platforms
+	template.Links = req.Links
+	template.Tags = models.NormalizeTags(req.Tags)
+	template.Variables = models.TemplateVariables(append([]string{req.Title, req.Content}, req.Links...)...)
+}
+
+// validSnippetName checks that the name can be referenced from a template and is
+// not taken by another snippet of the user
+func (h *Handler) validSnippetName(c *gin.Context, userID, name, snippetID string) bool {
+	if !models.ValidSnippetName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Snippet names may only contain letters, digits, '.', '_' and '-'"})
+		return false
+	}
+
+	snippets, err := h.db.GetUserSnippets(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get snippets"})
+		return false
+	}
+	for _, snippet := range snippets {
+		if snippet.Name == name && snippet.ID != snippetID {
+			c.JSON(http.StatusConflict, gin.H{"error": "A snippet with this name already exists"})
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) getOwnedTemplate(c *gin.Context, templateID string) (*models.PostTemplate, bool) {
+	template, err := h.db.GetTemplate(templateID)
+	if errors.Is(err, repository.ErrTemplateNotFound) || (err == nil && template.UserID != c.GetString("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get template"})
+		return nil, false
+	}
+	return template, true
+}
+
+func (h *Handler) getOwnedSnippet(c *gin.Context, snippetID string) (*models.Snippet, bool) {
+	snippet, err := h.db.GetSnippet(snippetID)
+	if errors.Is(err, repository.ErrSnippetNotFound) || (err == nil && snippet.UserID != c.GetString("user_id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get snippet"})
+		return nil, false
+	}
+	return snippet, true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trash pages hold trashPageSize posts unless the client asks for fewer or
+// more, up to maxTrashPageSize. Elasticsearch pages no further than
+// maxResultWindow posts.
+const (
+	trashPageSize    = 100
+	maxTrashPageSize = 1000
+	maxResultWindow  = 10000
+)
+
+func (h *Handler) GetTrash(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(trashPageSize)))
+	if err != nil || limit < 1 || limit > maxTrashPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxTrashPageSize)})
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 || offset+limit > maxResultWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+		return
+	}
+
+	posts, err := h.db.GetUserTrashedPosts(userID, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":          h.localizePosts(c, posts),
+		"offset":         offset,
+		"limit":          limit,
+		"retention_days": int(h.cfg.TrashRetention.Hours() / 24),
+	})
+}
+
+func (h *Handler) RestorePost(c *gin.Context) {
+	post, ok := h.findOwnedPost(c, c.Param("id"), true)
+	if !ok {
+		return
+	}
+
+	if err := h.db.RestorePost(post, c.GetString("user_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore post"})
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post restored successfully",
+		"post":    post,
+	})
+}
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/media"
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
+)
+
+// processBatchSize is the maximum number of images processed per run
+const processBatchSize = 20
+
+// MediaProcessor runs uploaded images through the image pipeline: it stores
+// their thumbnail and platform renditions. Metadata is removed before images
+// are first stored.
+type MediaProcessor struct {
+	db      *repository.ElasticsearchDB
+	storage storage.Storage
+}
+
+func NewMediaProcessor(db *repository.ElasticsearchDB, store storage.Storage) *MediaProcessor {
+	return &MediaProcessor{
+		db:      db,
+		storage: store,
+	}
+}
+
+// Run processes pending images every interval until the context is cancelled
+func (p *MediaProcessor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := p.ProcessOnce(ctx); err != nil {
+			log.Printf("Media processing failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Processed %d images", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessOnce processes a batch of pending images and returns how many were
+// processed. An image that cannot be processed is marked failed and keeps
+// being served as uploaded.
+func (p *MediaProcessor) ProcessOnce(ctx context.Context) (int, error) {
+	assets, err := p.db.GetPendingMedia(processBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i := range assets {
+		asset := &assets[i]
+		if err := p.process(ctx, asset); err != nil {
+			log.Printf("Failed to process media %s: %v", asset.ID, err)
+			asset.Processing = models.MediaProcessingFailed
+			asset.ProcessingError = err.Error()
+		} else {
+			asset.Processing = models.MediaProcessingDone
+			asset.ProcessingError = ""
+			processed++
+		}
+		err := p.db.UpdateMediaProcessing(asset)
+		if errors.Is(err, repository.ErrMediaNotFound) {
+			// Deleted while it was processed, which already released its usage
+			p.removeRenditions(ctx, asset)
+			continue
+		}
+		if err != nil {
+			return processed, err
+		}
+	}
+	return processed, nil
+}
+
+func (p *MediaProcessor) process(ctx context.Context, asset *models.MediaAsset) error {
+	file, err := p.storage.Open(ctx, asset.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(file, media.MaxImageSize+1))
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	result, err := media.ProcessImage(data)
+	if err != nil {
+		return err
+	}
+
+	renditions := make([]models.Rendition, 0, len(result.Renditions))
+	for _, r := range result.Renditions {
+		key := renditionKey(asset.Key, r.Name)
+		if err := p.storage.Put(ctx, key, bytes.NewReader(r.Data), int64(len(r.Data)), "image/jpeg"); err != nil {
+			return err
+		}
+		renditions = append(renditions, models.Rendition{
+			Name:   r.Name,
+			Key:    key,
+			URL:    p.storage.URL(key),
+			Width:  r.Width,
+			Height: r.Height,
+			Size:   int64(len(r.Data)),
+		})
+	}
+	asset.Width, asset.Height = result.Width, result.Height
+	asset.Renditions = renditions
+	return nil
+}
+
+// removeRenditions removes the renditions written for an asset that has
+// since been deleted
+func (p *MediaProcessor) removeRenditions(ctx context.Context, asset *models.MediaAsset) {
+	for _, r := range asset.Renditions {
+		p.removeUnused(ctx, r.Key)
+	}
+}
+
+// removeUnused removes a stored file that no asset or post uses anymore
+func (p *MediaProcessor) removeUnused(ctx context.Context, key string) {
+	inUse, err := p.db.MediaKeyInUse(key, "")
+	if err == nil && !inUse {
+		err = storage.Remove(ctx, p.storage, key, "")
+	}
+	if err != nil {
+		log.Printf("Failed to remove unused file %s: %v", key, err)
+	}
+}
+
+// renditionKey stores renditions next to their original
+func renditionKey(key, name string) string {
+	if i := strings.LastIndex(key, "."); i > strings.LastIndex(key, "/") {
+		key = key[:i]
+	}
+	return key + "_" + name + ".jpg"
+}
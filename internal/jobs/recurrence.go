@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+// seriesBatchSize is the maximum number of recurring series handled per run
+const seriesBatchSize = 1000
+
+// RecurrenceMaterializer creates the scheduled posts of recurring series
+// for every occurrence that falls within the horizon
+type RecurrenceMaterializer struct {
+	db      *repository.ElasticsearchDB
+	horizon time.Duration
+}
+
+func NewRecurrenceMaterializer(db *repository.ElasticsearchDB, horizon time.Duration) *RecurrenceMaterializer {
+	return &RecurrenceMaterializer{
+		db:      db,
+		horizon: horizon,
+	}
+}
+
+// Run materializes upcoming occurrences every interval until the context is cancelled
+func (m *RecurrenceMaterializer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := m.MaterializeOnce(); err != nil {
+			log.Printf("Recurrence materialization failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Materialized %d recurring post occurrences", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// MaterializeOnce materializes the upcoming occurrences of every active series
+// and returns how many posts were created
+func (m *RecurrenceMaterializer) MaterializeOnce() (int, error) {
+	series, err := m.db.GetActiveSeries(seriesBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for i := range series {
+		n, err := m.materialize(&series[i])
+		created += n
+		if errors.Is(err, repository.ErrVersionConflict) {
+			// The template was edited concurrently, pick it up on the next run
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to materialize series %s: %v", series[i].ID, err)
+		}
+	}
+	return created, nil
+}
+
+func (m *RecurrenceMaterializer) materialize(series *models.Post) (int, error) {
+	now := time.Now()
+	from := now
+	if until := series.Recurrence.MaterializedUntil; until != nil && until.After(from) {
+		from = *until
+	}
+
+	occurrences, err := series.Recurrence.Between(from, now.Add(m.horizon))
+	if err != nil || len(occurrences) == 0 {
+		return 0, err
+	}
+
+	created, advanced := 0, false
+	var createErr error
+	for _, at := range occurrences {
+		err := m.createOccurrence(series, at)
+		if errors.Is(err, repository.ErrOccurrenceExists) {
+			// Materialized by an earlier run whose progress was not saved, or kept
+			// from a previous schedule because it was edited on its own
+			err = nil
+		} else if err == nil {
+			created++
+		}
+		if err != nil {
+			createErr = err
+			break
+		}
+		materialized := at
+		series.Recurrence.MaterializedUntil = &materialized
+		advanced = true
+	}
+
+	// Persist progress even after a failure; occurrences that already exist
+	// are skipped on the next run either way
+	if advanced {
+		if err := m.db.UpdatePost(series, systemEditor); err != nil {
+			return created, err
+		}
+	}
+	return created, createErr
+}
+
+// createOccurrence materializes a single occurrence unless the series already
+// has a live post for it
+func (m *RecurrenceMaterializer) createOccurrence(series *models.Post, at time.Time) error {
+	post, err := series.NewOccurrence(at)
+	if err != nil {
+		return err
+	}
+	return m.db.CreateOccurrence(post, systemEditor)
+}
@@ -0,0 +1,364 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+	"github.com/priince9381/irm_backend/internal/publisher"
+	"github.com/priince9381/irm_backend/internal/repository"
+)
+
+// systemEditor is recorded as the editor of revisions written by background jobs
+const systemEditor = "system"
+
+// dueBatchSize is the maximum number of due posts published per run
+const dueBatchSize = 50
+
+// publishTimeout is how long a post may stay publishing before the attempt is
+// considered interrupted, e.g. by a restart
+const publishTimeout = 15 * time.Minute
+
+// maxCommentAttempts is how often posting a follow-up comment is tried before it is marked failed
+const maxCommentAttempts = 3
+
+// Scheduler publishes scheduled posts once their time has come
+type Scheduler struct {
+	db         *repository.ElasticsearchDB
+	publishers *publisher.Registry
+}
+
+func NewScheduler(db *repository.ElasticsearchDB, publishers *publisher.Registry) *Scheduler {
+	return &Scheduler{
+		db:         db,
+		publishers: publishers,
+	}
+}
+
+// Run publishes due posts every interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.RecoverInterrupted(); err != nil {
+			log.Printf("Recovering interrupted posts failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Marked %d interrupted posts as failed", n)
+		}
+		if n, err := s.PublishDue(ctx); err != nil {
+			log.Printf("Publishing due posts failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Published %d posts", n)
+		}
+		if n, err := s.PublishDueComments(ctx); err != nil {
+			log.Printf("Posting due comments failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Posted %d follow-up comments", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishDue publishes every post whose scheduled time has passed and returns
+// how many were published successfully. Posts to platforms without a
+// configured publisher stay scheduled.
+func (s *Scheduler) PublishDue(ctx context.Context) (int, error) {
+	var unavailable []string
+	for _, platform := range models.Platforms() {
+		if !s.publishers.Supports([]string{platform}) {
+			unavailable = append(unavailable, platform)
+		}
+	}
+	posts, err := s.db.GetDuePosts(time.Now(), unavailable, dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range posts {
+		if !s.publishers.Supports(posts[i].Platforms) {
+			continue
+		}
+		err := s.publish(ctx, &posts[i])
+		if errors.Is(err, repository.ErrVersionConflict) {
+			// Claimed or edited concurrently
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to publish post %s: %v", posts[i].ID, err)
+			continue
+		}
+		published++
+	}
+	return published, nil
+}
+
+// RecoverInterrupted marks posts that were left publishing for longer than
+// publishTimeout as failed so they can be rescheduled. Platforms already
+// published to keep their IDs and are skipped when the post is retried.
+func (s *Scheduler) RecoverInterrupted() (int, error) {
+	posts, err := s.db.GetPostsPublishingSince(time.Now().Add(-publishTimeout), dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for i := range posts {
+		post := &posts[i]
+		if err := post.TransitionTo(models.PostStatusFailed); err != nil {
+			return recovered, err
+		}
+		post.PublishError = "publishing was interrupted; reschedule the post to retry"
+		err := s.db.UpdatePost(post, systemEditor)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			// Finished concurrently
+			continue
+		}
+		if err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// publish claims a post by moving it to publishing, sends it to every platform
+// and records the outcome as published or failed
+func (s *Scheduler) publish(ctx context.Context, post *models.Post) error {
+	if err := post.TransitionTo(models.PostStatusPublishing); err != nil {
+		return err
+	}
+	if err := s.db.UpdatePost(post, systemEditor); err != nil {
+		return err
+	}
+
+	publishErr := s.publishPlatforms(ctx, post)
+
+	next := models.PostStatusPublished
+	post.PublishError = ""
+	if publishErr != nil {
+		next = models.PostStatusFailed
+		post.PublishError = publishErr.Error()
+	}
+	if err := post.TransitionTo(next); err != nil {
+		return err
+	}
+	if err := s.db.UpdatePost(post, systemEditor); err != nil {
+		return err
+	}
+	return publishErr
+}
+
+// publishPlatforms publishes the post to each of its platforms. Threads are
+// published part by part, each replying to the previous one. Platform IDs are
+// recorded as they are obtained so a retry skips what was already published.
+func (s *Scheduler) publishPlatforms(ctx context.Context, post *models.Post) error {
+	if post.PlatformIDs == nil {
+		post.PlatformIDs = make(map[string]string)
+	}
+	if err := s.describeMedia(post); err != nil {
+		return err
+	}
+
+	links := post.Links
+	if post.CampaignID != "" {
+		campaign, err := s.db.GetCampaign(post.CampaignID)
+		if err != nil && !errors.Is(err, repository.ErrCampaignNotFound) {
+			return err
+		}
+		if campaign != nil {
+			links = make([]string, len(post.Links))
+			for i, link := range post.Links {
+				links[i] = campaign.TagLink(link)
+			}
+		}
+	}
+
+	for _, platform := range post.Platforms {
+		client, err := s.publishers.Get(platform)
+		if err != nil {
+			return err
+		}
+		rules, _ := models.GetPlatformRules(platform)
+		thread := rules.PartsMode == models.PartsModeThread
+
+		if post.PlatformIDs[platform] == "" {
+			req := publisher.Request{
+				AccountID: post.AccountID,
+				Content:   post.Content,
+				Media:     models.MediaForPlatform(post.MediaFiles, platform),
+				Links:     links,
+			}
+			if !thread {
+				req.Parts = models.PartsForPlatform(post.Parts, platform)
+			}
+			id, err := client.Publish(ctx, req)
+			if err != nil {
+				return err
+			}
+			post.PlatformIDs[platform] = id
+		}
+		if !thread {
+			continue
+		}
+
+		previous := post.PlatformIDs[platform]
+		for i := range post.Parts {
+			part := &post.Parts[i]
+			if id := part.PlatformIDs[platform]; id != "" {
+				previous = id
+				continue
+			}
+
+			id, err := client.Publish(ctx, publisher.Request{
+				AccountID: post.AccountID,
+				Content:   part.Content,
+				Media:     models.MediaForPlatform(part.MediaFiles, platform),
+				ReplyToID: previous,
+			})
+			if err != nil {
+				return err
+			}
+			if part.PlatformIDs == nil {
+				part.PlatformIDs = make(map[string]string)
+			}
+			part.PlatformIDs[platform] = id
+			previous = id
+		}
+	}
+	return nil
+}
+
+// PublishDueComments posts the follow-up comments of published posts whose
+// offset has passed and returns how many were posted
+func (s *Scheduler) PublishDueComments(ctx context.Context) (int, error) {
+	now := time.Now()
+	posts, err := s.db.GetPostsWithDueComments(now, dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	for i := range posts {
+		post := &posts[i]
+		due := post.DueComments(now)
+		if len(due) == 0 {
+			// Saved before the due time was recorded; saving records it
+			if post.NextCommentAt == nil {
+				if err := s.db.UpdatePost(post, systemEditor); err != nil && !errors.Is(err, repository.ErrVersionConflict) {
+					log.Printf("Failed to save comments of post %s: %v", post.ID, err)
+				}
+			}
+			continue
+		}
+
+		for _, d := range due {
+			if s.publishComment(ctx, post, post.Comment(d.Comment.ID)) {
+				posted++
+			}
+		}
+
+		err := s.db.UpdatePost(post, systemEditor)
+		if err != nil && !errors.Is(err, repository.ErrVersionConflict) {
+			log.Printf("Failed to save comments of post %s: %v", post.ID, err)
+		}
+	}
+	return posted, nil
+}
+
+// publishComment posts a comment under the published post on each of its
+// platforms, recording the outcome on the comment. It reports whether the
+// comment is now fully posted.
+func (s *Scheduler) publishComment(ctx context.Context, post *models.Post, comment *models.FollowUpComment) bool {
+	if comment.PlatformIDs == nil {
+		comment.PlatformIDs = make(map[string]string)
+	}
+
+	var publishErr error
+	for _, platform := range post.CommentPlatforms(comment) {
+		if comment.PlatformIDs[platform] != "" {
+			continue
+		}
+		postID := post.PlatformIDs[platform]
+		if postID == "" {
+			publishErr = fmt.Errorf("post was not published on %s", platform)
+			break
+		}
+		client, err := s.publishers.Get(platform)
+		if err != nil {
+			publishErr = err
+			break
+		}
+		id, err := client.Publish(ctx, publisher.Request{
+			AccountID:   post.AccountID,
+			Content:     comment.Content,
+			CommentOnID: postID,
+		})
+		if err != nil {
+			publishErr = err
+			break
+		}
+		comment.PlatformIDs[platform] = id
+	}
+
+	comment.Attempts++
+	if publishErr != nil {
+		comment.Error = publishErr.Error()
+		if comment.Attempts >= maxCommentAttempts {
+			comment.Status = models.CommentStatusFailed
+		}
+		return false
+	}
+
+	now := time.Now()
+	comment.Status = models.CommentStatusPosted
+	comment.Error = ""
+	comment.PostedAt = &now
+	return true
+}
+
+// describeMedia copies the current alt text, file and renditions of library
+// media into the post, so descriptions edited and images processed after the
+// media was attached are published
+func (s *Scheduler) describeMedia(post *models.Post) error {
+	entries := make([]*models.Media, 0, len(post.MediaFiles))
+	for i := range post.MediaFiles {
+		entries = append(entries, &post.MediaFiles[i])
+	}
+	for i := range post.Parts {
+		for j := range post.Parts[i].MediaFiles {
+			entries = append(entries, &post.Parts[i].MediaFiles[j])
+		}
+	}
+
+	var ids []string
+	for _, media := range entries {
+		if media.ID != "" {
+			ids = append(ids, media.ID)
+		}
+	}
+	assets, err := s.db.GetMediaByIDs(ids)
+	if err != nil {
+		return err
+	}
+	for _, media := range entries {
+		if asset, ok := assets[media.ID]; ok {
+			media.AltText = asset.AltText
+			media.Key, media.URL = asset.Key, asset.URL
+			media.Processing = asset.Processing
+			media.Size = asset.Size
+			media.Width, media.Height = asset.Width, asset.Height
+			media.Renditions = asset.Renditions
+		}
+	}
+	return nil
+}
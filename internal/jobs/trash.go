@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
+)
+
+// purgeBatchSize is the number of trashed posts purged per query
+const purgeBatchSize = 100
+
+// TrashPurger permanently removes posts, and their uploaded files, that have
+// been in the trash for longer than the retention period
+type TrashPurger struct {
+	db        *repository.ElasticsearchDB
+	storage   storage.Storage
+	retention time.Duration
+}
+
+func NewTrashPurger(db *repository.ElasticsearchDB, store storage.Storage, retention time.Duration) *TrashPurger {
+	return &TrashPurger{
+		db:        db,
+		storage:   store,
+		retention: retention,
+	}
+}
+
+// Run purges the trash every interval until the context is cancelled
+func (p *TrashPurger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := p.PurgeOnce(); err != nil {
+			log.Printf("Trash purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d posts from trash", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce removes every post whose retention period has expired and
+// returns how many posts were purged
+func (p *TrashPurger) PurgeOnce() (int, error) {
+	cutoff := time.Now().Add(-p.retention)
+	purged := 0
+
+	for {
+		posts, err := p.db.GetPostsDeletedBefore(cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, post := range posts {
+			files := post.MediaFiles
+			for _, part := range post.Parts {
+				files = append(files, part.MediaFiles...)
+			}
+			// Occurrences of a recurring series share the files of their template
+			if post.SeriesID != "" {
+				files = nil
+			}
+			for _, media := range files {
+				// Library media outlives the posts using it
+				if media.ID != "" {
+					continue
+				}
+				// Duplicates share the files of the post they were copied from
+				inUse, err := p.db.MediaURLInUse(media.URL, post.ID)
+				if err != nil {
+					return purged, err
+				}
+				if inUse {
+					continue
+				}
+				if err := storage.Remove(context.Background(), p.storage, media.Key, media.URL); err != nil {
+					log.Printf("Failed to delete media file %s of post %s: %v", media.URL, post.ID, err)
+				}
+			}
+			if err := p.db.PurgePost(post.ID); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(posts) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/repository"
+	"github.com/priince9381/irm_backend/internal/storage"
+)
+
+const (
+	// reapBatchSize is the number of expired uploads removed per query
+	reapBatchSize = 100
+	// uploadReapGrace leaves time to complete a direct upload that finished
+	// just as its URL expired
+	uploadReapGrace = time.Hour
+)
+
+// UploadReaper removes uploads whose URL has expired, whether they were
+// completed or abandoned, together with the data received for them
+type UploadReaper struct {
+	db      *repository.ElasticsearchDB
+	storage storage.Storage
+	staging *storage.Staging
+}
+
+func NewUploadReaper(db *repository.ElasticsearchDB, store storage.Storage, staging *storage.Staging) *UploadReaper {
+	return &UploadReaper{
+		db:      db,
+		storage: store,
+		staging: staging,
+	}
+}
+
+// Run removes expired uploads every interval until the context is cancelled
+func (r *UploadReaper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := r.ReapOnce(); err != nil {
+			log.Printf("Upload cleanup failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Removed %d abandoned uploads", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReapOnce removes every expired upload and returns how many were removed
+func (r *UploadReaper) ReapOnce() (int, error) {
+	cutoff := time.Now().Add(-uploadReapGrace)
+	reaped := 0
+
+	for {
+		uploads, err := r.db.GetMediaUploadsExpiredBefore(cutoff, reapBatchSize)
+		if err != nil {
+			return reaped, err
+		}
+
+		for _, upload := range uploads {
+			if upload.Resumable {
+				if err := r.staging.Remove(upload.ID); err != nil {
+					return reaped, err
+				}
+			}
+
+			// Resumable uploads have no key. Direct uploads are received under a key
+			// of their own; older ones used the content-addressed key of an asset.
+			if upload.Key != "" {
+				inUse, err := r.db.MediaKeyInUse(upload.Key, "")
+				if err != nil {
+					return reaped, err
+				}
+				if !inUse {
+					if err := storage.Remove(context.Background(), r.storage, upload.Key, ""); err != nil {
+						return reaped, err
+					}
+				}
+			}
+
+			if err := r.db.DeleteMediaUpload(upload.ID); err != nil {
+				return reaped, err
+			}
+			reaped++
+		}
+
+		if len(uploads) < reapBatchSize {
+			return reaped, nil
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+
+	// Decoders of the accepted image formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// maxImagePixels refuses to decode images that would need too much memory
+const maxImagePixels = 50_000_000
+
+// renditionQuality is the JPEG quality of renditions
+const renditionQuality = 85
+
+// RenditionSpec describes a rendition generated for every image. Renditions
+// with an aspect ratio are center cropped to it; the others keep the aspect
+// ratio of the original. Images are scaled down to fit the maximum
+// dimensions but never scaled up.
+type RenditionSpec struct {
+	Name      string
+	AspectW   int
+	AspectH   int
+	MaxWidth  int
+	MaxHeight int
+}
+
+// RenditionSpecs are the renditions generated for uploaded images
+var RenditionSpecs = []RenditionSpec{
+	{Name: "thumbnail", MaxWidth: 320, MaxHeight: 320},
+	{Name: "large", MaxWidth: 2048, MaxHeight: 2048},
+	{Name: "square", AspectW: 1, AspectH: 1, MaxWidth: 1440, MaxHeight: 1440},
+	{Name: "portrait", AspectW: 4, AspectH: 5, MaxWidth: 1080, MaxHeight: 1350},
+	{Name: "landscape", AspectW: 16, AspectH: 9, MaxWidth: 1920, MaxHeight: 1080},
+}
+
+// ProcessedImage is the result of processing a stored image
+type ProcessedImage struct {
+	// Width and Height are zero when the format cannot be decoded
+	Width      int
+	Height     int
+	Renditions []Rendition
+}
+
+// Rendition is a JPEG version of an image
+type Rendition struct {
+	Name   string
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// CleanImage returns an uploaded image as it has to be stored: without its
+// metadata, which can contain the location a photo was taken at. JPEGs
+// rotated by their EXIF orientation are re-encoded upright, the others are
+// not re-encoded. Malformed images are ErrUnsupported.
+func CleanImage(data []byte, format Format) ([]byte, error) {
+	stripped, err := StripMetadata(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unreadable image: %v", ErrUnsupported, err)
+	}
+	if format != formatJPEG {
+		return stripped, nil
+	}
+	orientation := jpegOrientation(data)
+	if orientation == 1 {
+		return stripped, nil
+	}
+	img, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return encodeJPEG(orient(img, orientation), 92)
+}
+
+// ProcessImage generates the renditions of a stored image. Animated GIFs
+// only get a thumbnail, as renditions would lose the animation, and WebP
+// images, which cannot be decoded, get none.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	format, err := Sniff(data)
+	if err != nil {
+		return nil, err
+	}
+	if format.Type != "image" {
+		return nil, fmt.Errorf("%s is not an image", format.ContentType)
+	}
+
+	result := &ProcessedImage{}
+	if format == formatWebP {
+		return result, nil
+	}
+	img, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	result.Width, result.Height = img.Rect.Dx(), img.Rect.Dy()
+
+	for _, spec := range RenditionSpecs {
+		if format == formatGIF && spec.Name != "thumbnail" {
+			continue
+		}
+		rendition := render(img, spec)
+		encoded, err := encodeJPEG(rendition, renditionQuality)
+		if err != nil {
+			return nil, err
+		}
+		result.Renditions = append(result.Renditions, Rendition{
+			Name:   spec.Name,
+			Width:  rendition.Rect.Dx(),
+			Height: rendition.Rect.Dy(),
+			Data:   encoded,
+		})
+	}
+	return result, nil
+}
+
+// decode decodes an image unless it would need too much memory
+func decode(data []byte) (*image.RGBA, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large to process", config.Width, config.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return toRGBA(decoded), nil
+}
+
+// render crops and scales an image as described by a rendition spec
+func render(img *image.RGBA, spec RenditionSpec) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if spec.AspectW > 0 && spec.AspectH > 0 {
+		cw, ch := w, w*spec.AspectH/spec.AspectW
+		if ch > h {
+			cw, ch = h*spec.AspectW/spec.AspectH, h
+		}
+		x, y := (w-cw)/2, (h-ch)/2
+		img = img.SubImage(image.Rect(x, y, x+cw, y+ch)).(*image.RGBA)
+
+		// Derive the height from the aspect ratio so rounding does not skew it
+		tw := min(cw, spec.MaxWidth)
+		th := tw * spec.AspectH / spec.AspectW
+		if th > spec.MaxHeight {
+			th = spec.MaxHeight
+			tw = th * spec.AspectW / spec.AspectH
+		}
+		return scale(img, max(tw, 1), max(th, 1))
+	}
+
+	tw, th := w, h
+	if tw > spec.MaxWidth {
+		tw, th = spec.MaxWidth, h*spec.MaxWidth/w
+	}
+	if th > spec.MaxHeight {
+		tw, th = w*spec.MaxHeight/h, spec.MaxHeight
+	}
+	return scale(img, max(tw, 1), max(th, 1))
+}
+
+// toRGBA converts an image to RGBA with its origin at 0,0
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst
+}
+
+// scale resizes an image by averaging the source pixels covered by each
+// destination pixel, which suits scaling down
+func scale(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if sw == w && sh == h {
+		draw.Draw(dst, dst.Rect, src, src.Rect.Min, draw.Src)
+		return dst
+	}
+
+	span := func(i, n, sn int) (int, int) {
+		start, end := i*sn/n, (i+1)*sn/n
+		if end <= start {
+			end = start + 1
+		}
+		return start, end
+	}
+	for y := 0; y < h; y++ {
+		sy0, sy1 := span(y, h, sh)
+		for x := 0; x < w; x++ {
+			sx0, sx1 := span(x, w, sw)
+			var r, g, b, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.PixOffset(src.Rect.Min.X+sx0, src.Rect.Min.Y+sy)
+				for sx := sx0; sx < sx1; sx++ {
+					p := src.Pix[row : row+4 : row+4]
+					r += uint32(p[0])
+					g += uint32(p[1])
+					b += uint32(p[2])
+					a += uint32(p[3])
+					n++
+					row += 4
+				}
+			}
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// orient transforms an image as described by an EXIF orientation, making it upright
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			s, d := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
+
+// encodeJPEG encodes an image as JPEG, flattening transparency onto white
+func encodeJPEG(img *image.RGBA, quality int) ([]byte, error) {
+	flat := image.NewRGBA(img.Rect)
+	draw.Draw(flat, flat.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Rect, img, img.Rect.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
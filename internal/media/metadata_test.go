@@ -0,0 +1,101 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// jpegSegment encodes a marker segment with its length
+func jpegSegment(marker byte, payload string) []byte {
+	out := []byte{0xff, marker}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	return append(out, payload...)
+}
+
+func TestStripJPEG(t *testing.T) {
+	soi := []byte{0xff, 0xd8}
+	jfif := jpegSegment(0xe0, "JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	exif := jpegSegment(0xe1, "Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08GPS")
+	xmp := jpegSegment(0xe1, "http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>")
+	icc := jpegSegment(0xe2, "ICC_PROFILE\x00\x01\x01")
+	iptc := jpegSegment(0xed, "Photoshop 3.0\x00")
+	comment := jpegSegment(0xfe, "taken at home")
+	dqt := jpegSegment(0xdb, "\x00quantization")
+	// Scan data may contain bytes that look like metadata markers
+	scan := append(jpegSegment(0xda, "\x01\x01\x00\x00\x3f\x00"), "\x12\xff\x00\xff\xe1\x34\xff\xd9"...)
+
+	join := func(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:  "without metadata",
+			input: join(soi, jfif, dqt, scan),
+			want:  join(soi, jfif, dqt, scan),
+		},
+		{
+			name:  "exif, xmp, iptc and comments removed",
+			input: join(soi, jfif, exif, xmp, iptc, comment, dqt, scan),
+			want:  join(soi, jfif, dqt, scan),
+		},
+		{
+			name:  "color profile kept",
+			input: join(soi, exif, icc, dqt, scan),
+			want:  join(soi, icc, dqt, scan),
+		},
+		{
+			name:    "not a jpeg",
+			input:   []byte("\x89PNG\r\n\x1a\n"),
+			wantErr: true,
+		},
+		{
+			name:    "segment longer than the file",
+			input:   join(soi, exif[:10]),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StripMetadata(tt.input, formatJPEG)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StripMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, tt.want) {
+				t.Errorf("StripMetadata() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanImageOrientsJPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 40, 20)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// Orientation 6: the camera was rotated, the image displays rotated by 90 degrees
+	exif := jpegSegment(0xe1, "Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")
+	data := bytes.Join([][]byte{encoded.Bytes()[:2], exif, encoded.Bytes()[2:]}, nil)
+
+	got, err := CleanImage(data, formatJPEG)
+	if err != nil {
+		t.Fatalf("CleanImage() error = %v", err)
+	}
+	if bytes.Contains(got, []byte("Exif")) {
+		t.Error("CleanImage() kept the EXIF segment")
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 20 || config.Height != 40 {
+		t.Errorf("CleanImage() is %dx%d, want 20x40", config.Width, config.Height)
+	}
+}
@@ -0,0 +1,186 @@
+// Package media inspects uploaded media files: it recognizes their format
+// from their content and normalizes their names.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrUnsupported is returned for files that are not an accepted media format
+	ErrUnsupported = errors.New("unsupported media format")
+	// ErrTooLarge is returned for files above the size limit of their type
+	ErrTooLarge = errors.New("media file is too large")
+)
+
+// Upload size limits by media type, independent of the target platforms
+const (
+	MaxImageSize = 50 << 20
+	MaxVideoSize = 5 << 30
+)
+
+// SniffLen is the number of leading bytes Sniff looks at
+const SniffLen = 512
+
+// Format is a media file format recognized from its content
+type Format struct {
+	ContentType string
+	Type        string // image, video
+	Ext         string
+}
+
+var (
+	formatJPEG = Format{ContentType: "image/jpeg", Type: "image", Ext: ".jpg"}
+	formatPNG  = Format{ContentType: "image/png", Type: "image", Ext: ".png"}
+	formatGIF  = Format{ContentType: "image/gif", Type: "image", Ext: ".gif"}
+	formatWebP = Format{ContentType: "image/webp", Type: "image", Ext: ".webp"}
+	formatMP4  = Format{ContentType: "video/mp4", Type: "video", Ext: ".mp4"}
+	formatMOV  = Format{ContentType: "video/quicktime", Type: "video", Ext: ".mov"}
+)
+
+// formats lists the accepted formats by content type
+var formats = map[string]Format{
+	formatJPEG.ContentType: formatJPEG,
+	formatPNG.ContentType:  formatPNG,
+	formatGIF.ContentType:  formatGIF,
+	formatWebP.ContentType: formatWebP,
+	formatMP4.ContentType:  formatMP4,
+	formatMOV.ContentType:  formatMOV,
+}
+
+// FormatOf returns the accepted format with the given content type
+func FormatOf(contentType string) (Format, bool) {
+	format, ok := formats[strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))]
+	return format, ok
+}
+
+// Sniff recognizes the format of a file from its first SniffLen bytes. Files
+// that are not an accepted format are rejected with an error explaining why.
+func Sniff(header []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(header, []byte("\xff\xd8\xff")):
+		return formatJPEG, nil
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return formatPNG, nil
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return formatGIF, nil
+	case len(header) >= 12 && string(header[:4]) == "RIFF" && string(header[8:12]) == "WEBP":
+		return formatWebP, nil
+	case len(header) >= 12 && string(header[4:8]) == "ftyp":
+		switch brand := string(header[8:12]); brand {
+		case "qt  ":
+			return formatMOV, nil
+		case "heic", "heix", "mif1", "msf1", "avif":
+			return Format{}, fmt.Errorf("%w: HEIF/AVIF images are not supported, convert them to JPEG", ErrUnsupported)
+		case "M4A ", "M4B ", "M4P ":
+			return Format{}, fmt.Errorf("%w: audio files are not supported", ErrUnsupported)
+		default:
+			return formatMP4, nil
+		}
+	case len(header) >= 8 && (string(header[4:8]) == "moov" || string(header[4:8]) == "mdat" || string(header[4:8]) == "wide"):
+		// QuickTime files written before the ftyp atom existed
+		return formatMOV, nil
+	}
+
+	if reason := rejectReason(header); reason != "" {
+		return Format{}, fmt.Errorf("%w: %s", ErrUnsupported, reason)
+	}
+	return Format{}, fmt.Errorf("%w: allowed formats are JPEG, PNG, GIF, WebP, MP4 and MOV", ErrUnsupported)
+}
+
+// rejectReason explains why well known non-media content is rejected
+func rejectReason(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("MZ")),
+		bytes.HasPrefix(header, []byte("\x7fELF")),
+		bytes.HasPrefix(header, []byte("\xfe\xed\xfa\xce")), bytes.HasPrefix(header, []byte("\xfe\xed\xfa\xcf")),
+		bytes.HasPrefix(header, []byte("\xce\xfa\xed\xfe")), bytes.HasPrefix(header, []byte("\xcf\xfa\xed\xfe")),
+		bytes.HasPrefix(header, []byte("\xca\xfe\xba\xbe")):
+		return "executables are not allowed"
+	case bytes.HasPrefix(header, []byte("#!")):
+		return "scripts are not allowed"
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("Rar!")),
+		bytes.HasPrefix(header, []byte("7z\xbc\xaf\x27\x1c")), bytes.HasPrefix(header, []byte("\x1f\x8b")):
+		return "archives are not allowed"
+	case bytes.HasPrefix(header, []byte("%PDF")):
+		return "documents are not allowed"
+	}
+
+	text := bytes.ToLower(bytes.TrimLeft(bytes.TrimPrefix(header, []byte("\xef\xbb\xbf")), " \t\r\n"))
+	switch {
+	case bytes.Contains(text, []byte("<svg")):
+		return "SVG images are not allowed because they can contain scripts"
+	case bytes.HasPrefix(text, []byte("<")):
+		return "HTML and XML documents are not allowed"
+	}
+	return ""
+}
+
+// Check recognizes the format of a file and checks its size against the
+// limit of its media type
+func Check(header []byte, size int64) (Format, error) {
+	format, err := Sniff(header)
+	if err != nil {
+		return Format{}, err
+	}
+	if err := CheckSize(format.Type, size); err != nil {
+		return Format{}, err
+	}
+	return format, nil
+}
+
+// CheckSize checks a file size against the limit of a media type
+func CheckSize(mediaType string, size int64) error {
+	limit := int64(MaxImageSize)
+	if mediaType == "video" {
+		limit = MaxVideoSize
+	}
+	if size > limit {
+		return fmt.Errorf("%w: %s files are limited to %d MB", ErrTooLarge, mediaType, limit>>20)
+	}
+	return nil
+}
+
+// maxFileNameLength caps sanitized names, extension included
+const maxFileNameLength = 100
+
+// SanitizeFileName reduces a client supplied file name to letters, digits,
+// dashes and underscores, and gives it the extension of its actual format
+func SanitizeFileName(name string, format Format) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	var b strings.Builder
+	underscore := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+
+	base := strings.Trim(b.String(), "_-")
+	if maxBase := maxFileNameLength - len(format.Ext); len(base) > maxBase {
+		base = strings.TrimRight(truncateUTF8(base, maxBase), "_-")
+	}
+	if base == "" {
+		base = "file"
+	}
+	return base + format.Ext
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	for n > 0 && n < len(s) && s[n]&0xc0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,62 @@
+package media
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    Format
+		wantErr string
+	}{
+		{"jpeg", "\xff\xd8\xff\xe0", formatJPEG, ""},
+		{"png", "\x89PNG\r\n\x1a\n", formatPNG, ""},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", formatWebP, ""},
+		{"mp4", "\x00\x00\x00\x18ftypisom", formatMP4, ""},
+		{"mov", "\x00\x00\x00\x14ftypqt  ", formatMOV, ""},
+		{"heic", "\x00\x00\x00\x18ftypheic", Format{}, "HEIF/AVIF"},
+		{"executable", "MZ\x90\x00", Format{}, "executables"},
+		{"svg", "\xef\xbb\xbf <svg xmlns=\"http://www.w3.org/2000/svg\">", Format{}, "SVG"},
+		{"html", "<!DOCTYPE html>", Format{}, "HTML"},
+		{"text", "hello", Format{}, "allowed formats"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sniff([]byte(tt.header))
+			if tt.wantErr != "" {
+				if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Sniff() error = %v, want ErrUnsupported mentioning %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Sniff() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"holiday.png", "holiday.jpg"},
+		{"../../etc/passwd", "passwd.jpg"},
+		{"C:\\Users\\me\\photo.jpeg", "photo.jpg"},
+		{"my photo (1)!.jpg", "my_photo_1.jpg"},
+		{"???", "file.jpg"},
+		{strings.Repeat("a", 95) + "éé.jpg", strings.Repeat("a", 95) + ".jpg"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeFileName(tt.input, formatJPEG); got != tt.want {
+			t.Errorf("SanitizeFileName(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
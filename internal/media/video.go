@@ -0,0 +1,285 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxMoovSize caps the metadata box read into memory
+const maxMoovSize = 64 << 20
+
+// VideoInfo describes an MP4 or QuickTime video
+type VideoInfo struct {
+	DurationSeconds float64
+	// Width and Height are the display dimensions, rotation applied
+	Width      int
+	Height     int
+	VideoCodec string // sample entry type, e.g. avc1 (H.264) or hvc1 (HEVC)
+	AudioCodec string // e.g. mp4a (AAC), empty without an audio track
+	FrameRate  float64
+	Bitrate    int64 // bits per second over the whole file
+}
+
+// ParseVideo reads the metadata of an MP4 or QuickTime file of the given size.
+// Boxes before the metadata are skipped by seeking when r is an io.Seeker
+// and by reading otherwise; reading stops after the metadata.
+func ParseVideo(r io.Reader, size int64) (*VideoInfo, error) {
+	moov, err := findMoov(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unreadable video: %v", ErrUnsupported, err)
+	}
+	info, err := parseMoov(moov)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unreadable video: %v", ErrUnsupported, err)
+	}
+	if info.DurationSeconds > 0 {
+		info.Bitrate = int64(float64(size*8) / info.DurationSeconds)
+	}
+	return info, nil
+}
+
+// findMoov returns the payload of the top level moov box
+func findMoov(r io.Reader) ([]byte, error) {
+	var header [16]byte
+	for {
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, errors.New("no moov box")
+			}
+			return nil, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+		headerLen := int64(8)
+		switch size {
+		case 0:
+			if boxType != "moov" {
+				return nil, errors.New("no moov box")
+			}
+		case 1:
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerLen = 16
+		}
+		if size != 0 && size < headerLen {
+			return nil, fmt.Errorf("invalid size of %q box", boxType)
+		}
+
+		if boxType == "moov" {
+			if size == 0 {
+				return io.ReadAll(io.LimitReader(r, maxMoovSize))
+			}
+			if size-headerLen > maxMoovSize {
+				return nil, errors.New("moov box is too large")
+			}
+			moov := make([]byte, size-headerLen)
+			_, err := io.ReadFull(r, moov)
+			return moov, err
+		}
+
+		if seeker, ok := r.(io.Seeker); ok {
+			if _, err := seeker.Seek(size-headerLen, io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		} else if _, err := io.CopyN(io.Discard, r, size-headerLen); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// box is a child box within a parent payload
+type box struct {
+	typ     string
+	payload []byte
+}
+
+// children splits a payload into its boxes
+func children(data []byte) ([]box, error) {
+	var boxes []box
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, errors.New("truncated box")
+		}
+		size := uint64(binary.BigEndian.Uint32(data))
+		typ := string(data[4:8])
+		headerLen := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(data))
+		case 1:
+			if len(data) < 16 {
+				return nil, errors.New("truncated box")
+			}
+			size = binary.BigEndian.Uint64(data[8:])
+			headerLen = 16
+		}
+		if size < headerLen || size > uint64(len(data)) {
+			return nil, fmt.Errorf("invalid size of %q box", typ)
+		}
+		boxes = append(boxes, box{typ: typ, payload: data[headerLen:size]})
+		data = data[size:]
+	}
+	return boxes, nil
+}
+
+// child returns the first child box of a type
+func child(data []byte, path ...string) ([]byte, bool) {
+	for _, typ := range path {
+		boxes, err := children(data)
+		if err != nil {
+			return nil, false
+		}
+		found := false
+		for _, b := range boxes {
+			if b.typ == typ {
+				data, found = b.payload, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+func parseMoov(moov []byte) (*VideoInfo, error) {
+	info := &VideoInfo{}
+
+	if mvhd, ok := child(moov, "mvhd"); ok {
+		timescale, duration, err := fullBoxDuration(mvhd)
+		if err != nil {
+			return nil, err
+		}
+		if timescale > 0 {
+			info.DurationSeconds = float64(duration) / float64(timescale)
+		}
+	}
+	if info.DurationSeconds == 0 {
+		// Fragmented files may only state the duration in the movie extends header
+		if mehd, ok := child(moov, "mvex", "mehd"); ok && len(mehd) >= 8 {
+			var duration uint64
+			if mehd[0] == 1 && len(mehd) >= 12 {
+				duration = binary.BigEndian.Uint64(mehd[4:])
+			} else {
+				duration = uint64(binary.BigEndian.Uint32(mehd[4:]))
+			}
+			if mvhd, ok := child(moov, "mvhd"); ok {
+				if timescale, _, err := fullBoxDuration(mvhd); err == nil && timescale > 0 {
+					info.DurationSeconds = float64(duration) / float64(timescale)
+				}
+			}
+		}
+	}
+
+	boxes, err := children(moov)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range boxes {
+		if b.typ != "trak" {
+			continue
+		}
+		hdlr, ok := child(b.payload, "mdia", "hdlr")
+		if !ok || len(hdlr) < 12 {
+			continue
+		}
+		stsd, _ := child(b.payload, "mdia", "minf", "stbl", "stsd")
+		codec := sampleEntryType(stsd)
+
+		switch string(hdlr[8:12]) {
+		case "vide":
+			if info.VideoCodec != "" {
+				continue
+			}
+			info.VideoCodec = codec
+			if tkhd, ok := child(b.payload, "tkhd"); ok {
+				info.Width, info.Height = trackDimensions(tkhd)
+			}
+			info.FrameRate = frameRate(b.payload)
+		case "soun":
+			if info.AudioCodec == "" {
+				info.AudioCodec = codec
+			}
+		}
+	}
+
+	if info.VideoCodec == "" {
+		return nil, errors.New("no video track")
+	}
+	return info, nil
+}
+
+// fullBoxDuration reads the timescale and duration of an mvhd or mdhd box
+func fullBoxDuration(data []byte) (uint32, uint64, error) {
+	if len(data) < 1 {
+		return 0, 0, errors.New("truncated header box")
+	}
+	if data[0] == 1 {
+		if len(data) < 32 {
+			return 0, 0, errors.New("truncated header box")
+		}
+		return binary.BigEndian.Uint32(data[20:]), binary.BigEndian.Uint64(data[24:]), nil
+	}
+	if len(data) < 20 {
+		return 0, 0, errors.New("truncated header box")
+	}
+	return binary.BigEndian.Uint32(data[12:]), uint64(binary.BigEndian.Uint32(data[16:])), nil
+}
+
+// trackDimensions reads the presentation size of a track header, swapping
+// width and height for tracks rotated by 90 or 270 degrees
+func trackDimensions(tkhd []byte) (int, int) {
+	matrix := 40
+	if len(tkhd) > 0 && tkhd[0] == 1 {
+		matrix = 52
+	}
+	if len(tkhd) < matrix+44 {
+		return 0, 0
+	}
+	width := int(binary.BigEndian.Uint32(tkhd[matrix+36:]) >> 16)
+	height := int(binary.BigEndian.Uint32(tkhd[matrix+40:]) >> 16)
+
+	a := int32(binary.BigEndian.Uint32(tkhd[matrix:]))
+	d := int32(binary.BigEndian.Uint32(tkhd[matrix+16:]))
+	if a == 0 && d == 0 {
+		width, height = height, width
+	}
+	return width, height
+}
+
+// frameRate derives the average frame rate of a track from its sample count
+// and duration
+func frameRate(trak []byte) float64 {
+	mdhd, ok := child(trak, "mdia", "mdhd")
+	if !ok {
+		return 0
+	}
+	timescale, duration, err := fullBoxDuration(mdhd)
+	if err != nil || timescale == 0 || duration == 0 {
+		return 0
+	}
+	stts, ok := child(trak, "mdia", "minf", "stbl", "stts")
+	if !ok || len(stts) < 8 {
+		return 0
+	}
+
+	entries := int(binary.BigEndian.Uint32(stts[4:]))
+	var samples uint64
+	for i := 0; i < entries && 8+i*8+8 <= len(stts); i++ {
+		samples += uint64(binary.BigEndian.Uint32(stts[8+i*8:]))
+	}
+	return float64(samples) * float64(timescale) / float64(duration)
+}
+
+// sampleEntryType returns the codec of the first sample description
+func sampleEntryType(stsd []byte) string {
+	if len(stsd) < 16 {
+		return ""
+	}
+	return string(stsd[12:16])
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// mp4Box encodes a box with a 32-bit size
+func mp4Box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, typ...), body...)
+}
+
+// u32 encodes big-endian 32-bit values
+func u32(values ...uint32) []byte {
+	var out []byte
+	for _, v := range values {
+		out = binary.BigEndian.AppendUint32(out, v)
+	}
+	return out
+}
+
+// mvhdBox is a version 0 movie header
+func mvhdBox(timescale, duration uint32) []byte {
+	return mp4Box("mvhd", u32(0, 0, 0, timescale, duration), make([]byte, 80))
+}
+
+// tkhdBox is a version 0 track header, rotated by 90 degrees when asked
+func tkhdBox(width, height uint32, rotated bool) []byte {
+	matrix := u32(0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000)
+	if rotated {
+		matrix = u32(0, 0x10000, 0, 0xffff0000, 0, 0, 0, 0, 0x40000000)
+	}
+	return mp4Box("tkhd", make([]byte, 40), matrix, u32(width<<16, height<<16))
+}
+
+// trakBox is a track with a handler, a codec and a sample count
+func trakBox(handler, codec string, tkhd []byte, timescale, duration, samples uint32) []byte {
+	mdhd := mp4Box("mdhd", u32(0, 0, 0, timescale, duration), make([]byte, 4))
+	hdlr := mp4Box("hdlr", u32(0, 0), []byte(handler), make([]byte, 12))
+	stsd := mp4Box("stsd", u32(0, 1), mp4Box(codec, make([]byte, 8)))
+	stts := mp4Box("stts", u32(0, 1, samples, duration/samples))
+	stbl := mp4Box("stbl", stsd, stts)
+	return mp4Box("trak", tkhd, mp4Box("mdia", mdhd, hdlr, mp4Box("minf", stbl)))
+}
+
+// readerOnly hides the io.Seeker of the underlying reader
+type readerOnly struct{ io.Reader }
+
+func TestParseVideo(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("isom"), u32(0x200), []byte("isomiso2avc1mp41"))
+	mdat := mp4Box("mdat", make([]byte, 4096))
+	video := trakBox("vide", "avc1", tkhdBox(1920, 1080, false), 30000, 300000, 300)
+	audio := trakBox("soun", "mp4a", tkhdBox(0, 0, false), 48000, 480000, 469)
+
+	tests := []struct {
+		name     string
+		file     []byte
+		seekable bool
+		want     VideoInfo
+		wantErr  bool
+	}{
+		{
+			name:     "moov after mdat",
+			file:     bytes.Join([][]byte{ftyp, mdat, mp4Box("moov", mvhdBox(1000, 10000), video, audio)}, nil),
+			seekable: true,
+			want:     VideoInfo{DurationSeconds: 10, Width: 1920, Height: 1080, VideoCodec: "avc1", AudioCodec: "mp4a", FrameRate: 30},
+		},
+		{
+			name: "moov after mdat without seeking",
+			file: bytes.Join([][]byte{ftyp, mdat, mp4Box("moov", mvhdBox(1000, 10000), video, audio)}, nil),
+			want: VideoInfo{DurationSeconds: 10, Width: 1920, Height: 1080, VideoCodec: "avc1", AudioCodec: "mp4a", FrameRate: 30},
+		},
+		{
+			name: "rotated without audio",
+			file: bytes.Join([][]byte{ftyp, mp4Box("moov", mvhdBox(600, 1200),
+				trakBox("vide", "hvc1", tkhdBox(1920, 1080, true), 600, 1200, 60))}, nil),
+			want: VideoInfo{DurationSeconds: 2, Width: 1080, Height: 1920, VideoCodec: "hvc1", FrameRate: 30},
+		},
+
+		{
+			name:    "no video track",
+			file:    bytes.Join([][]byte{ftyp, mp4Box("moov", mvhdBox(1000, 10000), audio)}, nil),
+			wantErr: true,
+		},
+
+		{
+			name:    "truncated moov box",
+			file:    bytes.Join([][]byte{ftyp, mp4Box("moov", mvhdBox(1000, 10000), video)[:64]}, nil),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r io.Reader = readerOnly{bytes.NewReader(tt.file)}
+			if tt.seekable {
+				r = bytes.NewReader(tt.file)
+			}
+			got, err := ParseVideo(r, int64(len(tt.file)))
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupported) {
+					t.Fatalf("ParseVideo() error = %v, want ErrUnsupported", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseVideo() error = %v", err)
+			}
+
+			want := tt.want
+			want.Bitrate = int64(float64(len(tt.file)*8) / want.DurationSeconds)
+			if *got != want {
+				t.Errorf("ParseVideo() = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
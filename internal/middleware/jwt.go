@@ -124,9 +124,10 @@ func JWTAuth(secretKey string) gin.HandlerFunc {
 			return
 		}
 
-		// Set user ID and email in context
+		// Set user ID, email and role in context
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", claims.Role)
 
 		c.Next()
 	}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Bulk post actions
+const (
+	BulkActionDelete    = "delete"     // move to the trash
+	BulkActionStatus    = "status"     // change status
+	BulkActionShift     = "shift"      // move the schedule by an offset
+	BulkActionAddTag    = "add_tag"    // add a tag
+	BulkActionRemoveTag = "remove_tag" // remove a tag
+	BulkActionPlatforms = "platforms"  // replace the target platforms
+)
+
+// MaxBulkPostIDs caps the number of posts a bulk request may list by ID
+const MaxBulkPostIDs = 1000
+
+// BulkOperationRequest applies one action either to the listed posts or to
+// every post matching the filter
+type BulkOperationRequest struct {
+	Action        string      `json:"action" binding:"required,oneof=delete status shift add_tag remove_tag platforms"`
+	PostIDs       []string    `json:"post_ids"`
+	Filter        *BulkFilter `json:"filter"`
+	Status        string      `json:"status"`         // for status
+	OffsetSeconds int64       `json:"offset_seconds"` // for shift, may be negative
+	Tag           string      `json:"tag"`            // for add_tag and remove_tag
+	Platforms     []string    `json:"platforms"`      // for platforms
+}
+
+// BulkFilter selects posts like the listing filters
+type BulkFilter struct {
+	Tags      []string `json:"tags"`
+	Campaigns []string `json:"campaigns"`
+	Platforms []string `json:"platforms"`
+	Statuses  []string `json:"statuses"`
+}
+
+// PostFilter converts the filter to the one used by post queries
+func (f *BulkFilter) PostFilter() PostFilter {
+	return PostFilter{
+		Tags:        NormalizeTags(f.Tags),
+		CampaignIDs: f.Campaigns,
+		Platforms:   f.Platforms,
+		Statuses:    f.Statuses,
+	}
+}
+
+// BulkItemResult is the outcome of a bulk action on one post
+type BulkItemResult struct {
+	PostID string `json:"post_id"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkByQueryResult is the outcome of a bulk action applied through a filter.
+// Posts the action does not apply to, such as published posts for a shift,
+// are not matched. Failed lists the posts that matched but could not be updated.
+type BulkByQueryResult struct {
+	Updated          int              `json:"updated"`
+	VersionConflicts int              `json:"version_conflicts"`
+	Failed           []BulkItemResult `json:"failed"`
+}
+
+// Validate checks the target selection and the parameters of the action
+func (r *BulkOperationRequest) Validate() error {
+	if (len(r.PostIDs) == 0) == (r.Filter == nil) {
+		return errors.New("either post_ids or filter is required")
+	}
+	if len(r.PostIDs) > MaxBulkPostIDs {
+		return fmt.Errorf("at most %d post IDs are allowed", MaxBulkPostIDs)
+	}
+
+	switch r.Action {
+	case BulkActionStatus:
+		switch r.Status {
+		case PostStatusDraft, PostStatusScheduled, PostStatusArchived:
+		default:
+			return fmt.Errorf("status must be %s, %s or %s", PostStatusDraft, PostStatusScheduled, PostStatusArchived)
+		}
+	case BulkActionShift:
+		if r.OffsetSeconds == 0 {
+			return errors.New("offset_seconds is required")
+		}
+	case BulkActionAddTag, BulkActionRemoveTag:
+		tags := NormalizeTags([]string{r.Tag})
+		if len(tags) == 0 {
+			return errors.New("tag is required")
+		}
+		r.Tag = tags[0]
+	case BulkActionPlatforms:
+		if len(r.Platforms) == 0 {
+			return errors.New("at least one platform is required")
+		}
+		if r.Filter != nil {
+			// Every post has to be checked against the limits of the new platforms
+			return errors.New("platforms can only be changed for posts listed by ID")
+		}
+	}
+	return nil
+}
+
+// Apply performs the action on a single post with the same checks as an
+// individual update
+func (r *BulkOperationRequest) Apply(p *Post) error {
+	if r.Action == BulkActionDelete {
+		now := time.Now()
+		p.DeletedAt = &now
+		return nil
+	}
+
+	switch r.Action {
+	case BulkActionStatus:
+		if p.Status == r.Status {
+			return nil
+		}
+		if p.Recurrence != nil && r.Status == PostStatusScheduled {
+			return errors.New("recurring series templates cannot be scheduled directly")
+		}
+		if err := p.TransitionTo(r.Status); err != nil {
+			return err
+		}
+		if p.Status == PostStatusScheduled && p.ScheduledTime.IsZero() {
+			return errors.New("scheduled time is required for scheduled posts")
+		}
+	case BulkActionShift:
+		if p.Status != PostStatusDraft && p.Status != PostStatusScheduled {
+			return fmt.Errorf("%s posts cannot be rescheduled", p.Status)
+		}
+		if p.ScheduledTime.IsZero() {
+			return errors.New("post has no scheduled time")
+		}
+		p.ScheduledTime = p.ScheduledTime.Add(time.Duration(r.OffsetSeconds) * time.Second)
+		if p.Status == PostStatusScheduled && !p.ScheduledTime.After(time.Now()) {
+			return errors.New("shifted scheduled time is in the past")
+		}
+	case BulkActionAddTag:
+		p.Tags = NormalizeTags(append(p.Tags, r.Tag))
+	case BulkActionRemoveTag:
+		tags := []string{}
+		for _, tag := range p.Tags {
+			if tag != r.Tag {
+				tags = append(tags, tag)
+			}
+		}
+		p.Tags = tags
+	case BulkActionPlatforms:
+		p.Platforms = r.Platforms
+		if err := p.ValidateForPlatforms(); err != nil {
+			return err
+		}
+	}
+
+	if p.SeriesID != "" {
+		// Editing a single occurrence detaches it from later changes to the series
+		p.Detached = true
+	}
+	return nil
+}
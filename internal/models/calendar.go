@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarQuery selects the posts shown in a calendar view
+type CalendarQuery struct {
+	From       time.Time
+	To         time.Time
+	Interval   string // day, week
+	Field      string // scheduled_time, published_at
+	Timezone   string
+	Platforms  []string
+	AccountIDs []string
+	Statuses   []string
+	Tags       []string
+	Campaigns  []string
+}
+
+// CalendarBucket holds the posts falling on one day or week of the calendar
+type CalendarBucket struct {
+	Date  time.Time `json:"date"`
+	Count int       `json:"count"`
+	Posts []Post    `json:"posts"`
+}
+
+// RescheduleRequest moves a post to a new time without touching anything else.
+// Either ScheduledTime (RFC3339) or LocalScheduledTime with Timezone is required.
+type RescheduleRequest struct {
+	ScheduledTime      string `json:"scheduled_time"`
+	LocalScheduledTime string `json:"local_scheduled_time"`
+	Timezone           string `json:"timezone"`
+	DST                string `json:"dst" binding:"omitempty,oneof=reject earlier later compatible"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Campaign groups posts working towards a common goal over a date range
+type Campaign struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Goal      string     `json:"goal,omitempty"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   time.Time  `json:"end_date"`
+	UTM       UTMParams  `json:"utm"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// UTMParams are the tracking parameters added to the links of campaign posts
+type UTMParams struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+type CampaignRequest struct {
+	Name      string    `json:"name" binding:"required"`
+	Goal      string    `json:"goal"`
+	StartDate time.Time `json:"start_date" binding:"required"`
+	EndDate   time.Time `json:"end_date" binding:"required,gtfield=StartDate"`
+	UTM       UTMParams `json:"utm"`
+}
+
+// TagLink adds the campaign UTM parameters to a link, keeping any that the link already sets
+func (c *Campaign) TagLink(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+
+	q := u.Query()
+	for key, value := range map[string]string{
+		"utm_source":   c.UTM.Source,
+		"utm_medium":   c.UTM.Medium,
+		"utm_campaign": c.UTM.Campaign,
+		"utm_term":     c.UTM.Term,
+		"utm_content":  c.UTM.Content,
+	} {
+		if value != "" && q.Get(key) == "" {
+			q.Set(key, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// NormalizeTags lowercases and trims tags, dropping empty and duplicate ones
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// PostFilter narrows down the posts of a user
+type PostFilter struct {
+	Query       string // full text search on title and content
+	Tags        []string
+	CampaignIDs []string
+	Platforms   []string
+	Statuses    []string
+}
+
+// TagCount is the number of posts carrying a tag
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// AnalyticsRollup sums the analytics of the posts sharing a tag or campaign
+type AnalyticsRollup struct {
+	Key        string  `json:"key"`
+	Posts      int     `json:"posts"`
+	Likes      int     `json:"likes"`
+	Comments   int     `json:"comments"`
+	Shares     int     `json:"shares"`
+	Reach      int     `json:"reach"`
+	Engagement float64 `json:"engagement"` // average engagement of the posts in the group
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Follow-up comment statuses
+const (
+	CommentStatusPending = "pending"
+	CommentStatusPosted  = "posted"
+	CommentStatusFailed  = "failed"
+)
+
+// FollowUpComment is a comment posted under a post some time after it was published,
+// e.g. the hashtag block of an Instagram post
+type FollowUpComment struct {
+	ID            string            `json:"id"`
+	Content       string            `json:"content"`
+	OffsetSeconds int               `json:"offset_seconds"`      // delay after the post is published
+	Platforms     []string          `json:"platforms,omitempty"` // defaults to the platforms of the post
+	Status        string            `json:"status"`
+	PlatformIDs   map[string]string `json:"platform_ids,omitempty"` // platform -> published comment ID
+	Error         string            `json:"error,omitempty"`
+	Attempts      int               `json:"attempts"`
+	PostedAt      *time.Time        `json:"posted_at,omitempty"`
+}
+
+type FollowUpCommentRequest struct {
+	Content   string   `json:"content" binding:"required"`
+	Offset    string   `json:"offset"` // "immediately", "+1h", "30m"
+	Platforms []string `json:"platforms"`
+}
+
+// DueComment is a follow-up comment whose post has been published and whose offset has passed
+type DueComment struct {
+	PostID  string          `json:"post_id"`
+	DueAt   time.Time       `json:"due_at"`
+	Comment FollowUpComment `json:"comment"`
+}
+
+// NewFollowUpComments builds pending comments from their request form
+func NewFollowUpComments(reqs []FollowUpCommentRequest) ([]FollowUpComment, error) {
+	comments := make([]FollowUpComment, 0, len(reqs))
+	for i, req := range reqs {
+		if strings.TrimSpace(req.Content) == "" {
+			return nil, fmt.Errorf("comment %d is empty", i+1)
+		}
+		offset, err := parseCommentOffset(req.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("comment %d: %v", i+1, err)
+		}
+		comments = append(comments, FollowUpComment{
+			ID:            uuid.New().String(),
+			Content:       req.Content,
+			OffsetSeconds: int(offset / time.Second),
+			Platforms:     req.Platforms,
+			Status:        CommentStatusPending,
+		})
+	}
+	return comments, nil
+}
+
+// parseCommentOffset accepts "immediately", an empty string or a duration such as "+1h"
+func parseCommentOffset(offset string) (time.Duration, error) {
+	offset = strings.TrimSpace(offset)
+	if offset == "" || offset == "immediately" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(strings.TrimPrefix(offset, "+"))
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid offset %q", offset)
+	}
+	return d, nil
+}
+
+// CommentPlatforms returns the platforms a comment goes to
+func (p *Post) CommentPlatforms(comment *FollowUpComment) []string {
+	if len(comment.Platforms) > 0 {
+		return comment.Platforms
+	}
+	return p.Platforms
+}
+
+// DueComments returns the pending comments of a published post that are due at now
+func (p *Post) DueComments(now time.Time) []DueComment {
+	if p.Status != PostStatusPublished || p.PublishedAt == nil {
+		return nil
+	}
+
+	var due []DueComment
+	for _, comment := range p.Comments {
+		if comment.Status != CommentStatusPending {
+			continue
+		}
+		dueAt := p.PublishedAt.Add(time.Duration(comment.OffsetSeconds) * time.Second)
+		if !dueAt.After(now) {
+			due = append(due, DueComment{PostID: p.ID, DueAt: dueAt, Comment: comment})
+		}
+	}
+	return due
+}
+
+// NextCommentDue returns when the earliest pending comment of a published
+// post is due, or nil when there is none
+func (p *Post) NextCommentDue() *time.Time {
+	if p.Status != PostStatusPublished || p.PublishedAt == nil {
+		return nil
+	}
+
+	var next *time.Time
+	for _, comment := range p.Comments {
+		if comment.Status != CommentStatusPending {
+			continue
+		}
+		dueAt := p.PublishedAt.Add(time.Duration(comment.OffsetSeconds) * time.Second)
+		if next == nil || dueAt.Before(*next) {
+			next = &dueAt
+		}
+	}
+	return next
+}
+
+// Comment returns the follow-up comment with the given ID
+func (p *Post) Comment(commentID string) *FollowUpComment {
+	for i := range p.Comments {
+		if p.Comments[i].ID == commentID {
+			return &p.Comments[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// ExportQuery selects the posts of an export. From and To bound the scheduled
+// time and are ignored when zero.
+type ExportQuery struct {
+	Filter PostFilter
+	From   time.Time
+	To     time.Time
+}
+
+// PostExport is a post joined with its summed analytics
+type PostExport struct {
+	Post       Post    `json:"post"`
+	Likes      int     `json:"likes"`
+	Comments   int     `json:"comments"`
+	Shares     int     `json:"shares"`
+	Reach      int     `json:"reach"`
+	Engagement float64 `json:"engagement"`
+}
+
+// ExportColumns are the column headers of tabular exports, matching PostExport.Values
+var ExportColumns = []string{
+	"id", "title", "content", "platforms", "status", "scheduled_time", "published_at",
+	"campaign_id", "tags", "links", "likes", "comments", "shares", "reach", "engagement",
+}
+
+// Values returns the cells of a tabular export row with times rendered in loc.
+// List fields are joined with "|", the separator used by imports.
+func (e *PostExport) Values(loc *time.Location) []interface{} {
+	formatTime := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return ""
+		}
+		return t.In(loc).Format(time.RFC3339)
+	}
+	p := &e.Post
+	return []interface{}{
+		p.ID, p.Title, p.Content, strings.Join(p.Platforms, "|"), p.Status,
+		formatTime(&p.ScheduledTime), formatTime(p.PublishedAt),
+		p.CampaignID, strings.Join(p.Tags, "|"), strings.Join(p.Links, "|"),
+		e.Likes, e.Comments, e.Shares, e.Reach, e.Engagement,
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxImportRows caps the number of posts in a single import
+const MaxImportRows = 1000
+
+// ImportRow is one post of a bulk import. In CSV files the list columns
+// (platforms, links, media_ids, tags) hold values separated by "|".
+type ImportRow struct {
+	Title         string   `json:"title"`
+	Content       string   `json:"content"`
+	Platforms     []string `json:"platforms"`
+	Links         []string `json:"links"`
+	ScheduledTime string   `json:"scheduled_time"` // RFC3339 or local wall-clock time
+	Timezone      string   `json:"timezone"`
+	MediaIDs      []string `json:"media_ids"`  // library media to attach
+	MediaURLs     []string `json:"media_urls"` // rejected, remote media has to be uploaded to the library
+	Tags          []string `json:"tags"`
+	Status        string   `json:"status"` // draft or scheduled, scheduled when a time is given
+}
+
+// ImportRowError reports why a row could not be imported. Rows are numbered
+// from 1, not counting the CSV header.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarizes a bulk import
+type ImportResult struct {
+	DryRun   bool             `json:"dry_run"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int              `json:"imported"`
+	Errors   []ImportRowError `json:"errors"`
+	PostIDs  []string         `json:"post_ids,omitempty"`
+}
+
+var importColumns = map[string]bool{
+	"title": true, "content": true, "platforms": true, "links": true, "scheduled_time": true,
+	"timezone": true, "media_ids": true, "media_urls": true, "tags": true, "status": true,
+}
+
+// ParseImportCSV reads import rows from a CSV file with a header row
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		if !importColumns[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("imports are limited to %d rows", MaxImportRows)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		rows = append(rows, ImportRow{
+			Title:         field("title"),
+			Content:       field("content"),
+			Platforms:     splitImportList(field("platforms")),
+			Links:         splitImportList(field("links")),
+			ScheduledTime: field("scheduled_time"),
+			Timezone:      field("timezone"),
+			MediaIDs:      splitImportList(field("media_ids")),
+			MediaURLs:     splitImportList(field("media_urls")),
+			Tags:          splitImportList(field("tags")),
+			Status:        field("status"),
+		})
+	}
+	return rows, nil
+}
+
+// ParseImportNDJSON reads import rows from newline-delimited JSON objects.
+// Blank lines are skipped.
+func ParseImportNDJSON(r io.Reader) ([]ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []ImportRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("imports are limited to %d rows", MaxImportRows)
+		}
+
+		var row ImportRow
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return nil, fmt.Errorf("invalid JSON on line %d: %v", line, err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid NDJSON: %w", err)
+	}
+	return rows, nil
+}
+
+func splitImportList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, "|") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
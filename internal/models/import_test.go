@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []ImportRow
+		wantErr bool
+	}{
+		{
+			name: "lists and trimming",
+			input: "title,content,platforms,media_ids,scheduled_time,timezone\n" +
+				"Launch, Out now ,twitter| linkedin ,m1||m2,2024-03-04 09:00,Europe/Berlin\n",
+			want: []ImportRow{{
+				Title:         "Launch",
+				Content:       "Out now",
+				Platforms:     []string{"twitter", "linkedin"},
+				MediaIDs:      []string{"m1", "m2"},
+				ScheduledTime: "2024-03-04 09:00",
+				Timezone:      "Europe/Berlin",
+			}},
+		},
+		{
+			name:  "byte order mark and case in header",
+			input: "\ufeffTitle,Content\nHello,World\n",
+			want:  []ImportRow{{Title: "Hello", Content: "World"}},
+		},
+		{
+			name:    "unknown column",
+			input:   "title,body\nHello,World\n",
+			wantErr: true,
+		},
+		{
+			name:    "too many rows",
+			input:   "title\n" + strings.Repeat("Hello\n", MaxImportRows+1),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImportCSV(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImportCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseImportCSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseImportNDJSON(t *testing.T) {
+	got, err := ParseImportNDJSON(strings.NewReader("{\"title\":\"One\",\"media_ids\":[\"m1\"]}\n\n  \n{\"title\":\"Two\",\"status\":\"draft\"}"))
+	if err != nil {
+		t.Fatalf("ParseImportNDJSON() error = %v", err)
+	}
+	want := []ImportRow{{Title: "One", MediaIDs: []string{"m1"}}, {Title: "Two", Status: "draft"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseImportNDJSON() = %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseImportNDJSON(strings.NewReader("{\"title\":\"One\"}\n\n{\"title\":")); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ParseImportNDJSON() error = %v, want the invalid line", err)
+	}
+}
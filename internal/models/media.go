@@ -0,0 +1,152 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Image processing states of a media asset
+const (
+	MediaProcessingPending = "pending"
+	MediaProcessingDone    = "done"
+	MediaProcessingFailed  = "failed"
+)
+
+// Rendition is a scaled or cropped JPEG version of an image
+type Rendition struct {
+	Name   string `json:"name"` // thumbnail, large, square, portrait, landscape
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
+}
+
+// VideoInfo is the metadata of a video read from its container
+type VideoInfo struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	VideoCodec      string  `json:"video_codec"` // e.g. avc1 (H.264), hvc1 (HEVC)
+	AudioCodec      string  `json:"audio_codec,omitempty"`
+	FrameRate       float64 `json:"frame_rate,omitempty"`
+	Bitrate         int64   `json:"bitrate"` // bits per second
+}
+
+// MediaAsset is an uploaded file in a user's media library. Posts reference
+// assets by ID through their Media entries, so one asset can be reused.
+type MediaAsset struct {
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id"`
+	Key         string      `json:"key"`
+	URL         string      `json:"url"`
+	Type        string      `json:"type"` // image, video
+	FileName    string      `json:"file_name"`
+	ContentType string      `json:"content_type"`
+	Size        int64       `json:"size"`
+	SHA256      string      `json:"sha256,omitempty"` // of the file as uploaded
+	Width       int         `json:"width,omitempty"`
+	Height      int         `json:"height,omitempty"`
+	Renditions  []Rendition `json:"renditions,omitempty"`
+	Video       *VideoInfo  `json:"video,omitempty"`
+	// Processing is the state of the image pipeline, empty for videos
+	Processing      string    `json:"processing,omitempty"`
+	ProcessingError string    `json:"processing_error,omitempty"`
+	Tags            []string  `json:"tags,omitempty"`
+	AltText         string    `json:"alt_text,omitempty"`
+	Caption         string    `json:"caption,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Reference returns the entry a post stores for the asset
+func (a *MediaAsset) Reference() Media {
+	return Media{
+		ID:          a.ID,
+		Key:         a.Key,
+		URL:         a.URL,
+		Type:        a.Type,
+		FileName:    a.FileName,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		Width:       a.Width,
+		Height:      a.Height,
+		Renditions:  a.Renditions,
+		Video:       a.Video,
+		AltText:     a.AltText,
+		Processing:  a.Processing,
+	}
+}
+
+// MediaTypeFromContentType maps a MIME type to a media type
+func MediaTypeFromContentType(contentType string) string {
+	if strings.HasPrefix(contentType, "video/") {
+		return "video"
+	}
+	return "image"
+}
+
+// DescribeMediaRequest updates the descriptive fields of an asset. Omitted
+// fields are left unchanged.
+type DescribeMediaRequest struct {
+	AltText *string  `json:"alt_text"`
+	Caption *string  `json:"caption"`
+	Tags    []string `json:"tags"`
+}
+
+// MediaQuery narrows down the media library of a user
+type MediaQuery struct {
+	Query string // matches file name, alt text and caption
+	Type  string
+	Tags  []string
+}
+
+// MediaUpload is an upload that was started but not yet completed. Direct
+// uploads go to storage and are checked when the client completes them;
+// resumable uploads are received in chunks and complete with the last one.
+type MediaUpload struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Key         string     `json:"key,omitempty"` // where a direct upload is received; empty for resumable uploads
+	FileName    string     `json:"file_name"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	SHA256      string     `json:"sha256,omitempty"` // declared for direct uploads
+	Resumable   bool       `json:"resumable"`
+	ExpiresAt   time.Time  `json:"expires_at"` // of the upload URL, or of an idle resumable upload
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateMediaUploadRequest declares a file the client is going to upload directly
+type CreateMediaUploadRequest struct {
+	FileName    string `json:"file_name" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	Size        int64  `json:"size" binding:"required,gt=0"`
+	SHA256      string `json:"sha256" binding:"required,len=64,hexadecimal"` // hex encoded
+}
+
+// ParseUploadMetadata decodes a tus Upload-Metadata header: comma separated
+// pairs of a key and an optional base64 encoded value
+func ParseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of upload metadata %q", fields[0])
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, errors.New("invalid upload metadata")
+		}
+	}
+	return metadata, nil
+}
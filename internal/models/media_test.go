@@ -0,0 +1,29 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"filename YS5qcGc=, is_confidential,,", map[string]string{"filename": "a.jpg", "is_confidential": ""}, false},
+		{"filename a.jpg", nil, true},
+		{"filename YS5qcGc= extra", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseUploadMetadata(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseUploadMetadata(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseUploadMetadata(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -37,12 +37,12 @@ type SocialAccount struct {
 
 type Analytics struct {
 	Base
-	PostID     uuid.UUID `gorm:"type:uuid;not null"`
-	Platform   string    `gorm:"not null"`
-	Likes      int       `gorm:"not null;default:0"`
-	Comments   int       `gorm:"not null;default:0"`
-	Shares     int       `gorm:"not null;default:0"`
-	Reach      int       `gorm:"not null;default:0"`
-	Engagement float64   `gorm:"not null;default:0"`
-	RecordedAt time.Time `gorm:"not null"`
+	PostID     uuid.UUID `json:"post_id" gorm:"type:uuid;not null"`
+	Platform   string    `json:"platform" gorm:"not null"`
+	Likes      int       `json:"likes" gorm:"not null;default:0"`
+	Comments   int       `json:"comments" gorm:"not null;default:0"`
+	Shares     int       `json:"shares" gorm:"not null;default:0"`
+	Reach      int       `json:"reach" gorm:"not null;default:0"`
+	Engagement float64   `json:"engagement" gorm:"not null;default:0"`
+	RecordedAt time.Time `json:"recorded_at" gorm:"not null"`
 }
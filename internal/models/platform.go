@@ -0,0 +1,330 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// How a platform publishes the parts of a multi-part post
+const (
+	PartsModeNone     = ""         // multi-part posts are not supported
+	PartsModeThread   = "thread"   // each part is a reply to the previous one
+	PartsModeCarousel = "carousel" // all parts are published together as one post
+)
+
+// PlatformRules describes the content limits of a social platform
+type PlatformRules struct {
+	MaxChars        int
+	MaxParts        int
+	MaxMediaPerPart int
+	PartsMode       string
+	// PartsRequireMedia is set for carousels, where every slide is a media item
+	PartsRequireMedia bool
+
+	// MediaTypes lists the accepted content types of media files
+	MediaTypes   []string
+	MaxImageSize int64
+	MaxVideoSize int64
+	// Accepted image aspect ratios (width / height) and dimensions, zero when unlimited
+	MinImageAspect    float64
+	MaxImageAspect    float64
+	MaxImageDimension int
+
+	// Video limits, zero or empty when unlimited. Durations are in seconds,
+	// bitrates in bits per second.
+	MinVideoDuration  float64
+	MaxVideoDuration  float64
+	VideoCodecs       []string
+	MaxVideoDimension int
+	MaxVideoFrameRate float64
+	MaxVideoBitrate   int64
+}
+
+// Media types accepted by the platforms
+var (
+	commonMediaTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "video/mp4", "video/quicktime"}
+	metaMediaTypes   = []string{"image/jpeg", "image/png", "video/mp4", "video/quicktime"}
+)
+
+// Video codecs accepted by the platforms, as sample entry types
+var (
+	h264Codecs     = []string{"avc1", "avc3"}
+	h264HEVCCodecs = []string{"avc1", "avc3", "hvc1", "hev1"}
+)
+
+var platformRules = map[string]PlatformRules{
+	"twitter": {
+		MaxChars: 280, MaxParts: 25, MaxMediaPerPart: 4, PartsMode: PartsModeThread,
+		MediaTypes: commonMediaTypes, MaxImageSize: 5 << 20, MaxVideoSize: 512 << 20,
+		MaxImageDimension: 4096, MinVideoDuration: 0.5, MaxVideoDuration: 140, VideoCodecs: h264Codecs,
+		MaxVideoDimension: 1920, MaxVideoFrameRate: 60, MaxVideoBitrate: 25_000_000,
+	},
+	"x": {
+		MaxChars: 280, MaxParts: 25, MaxMediaPerPart: 4, PartsMode: PartsModeThread,
+		MediaTypes: commonMediaTypes, MaxImageSize: 5 << 20, MaxVideoSize: 512 << 20,
+		MaxImageDimension: 4096, MinVideoDuration: 0.5, MaxVideoDuration: 140, VideoCodecs: h264Codecs,
+		MaxVideoDimension: 1920, MaxVideoFrameRate: 60, MaxVideoBitrate: 25_000_000,
+	},
+	"threads": {
+		MaxChars: 500, MaxParts: 20, MaxMediaPerPart: 10, PartsMode: PartsModeThread,
+		MediaTypes: metaMediaTypes, MaxImageSize: 8 << 20, MaxVideoSize: 1 << 30,
+		MinImageAspect: 0.8, MaxImageAspect: 1.91,
+		MaxVideoDuration: 300, VideoCodecs: h264HEVCCodecs,
+		MaxVideoDimension: 1920, MaxVideoFrameRate: 60, MaxVideoBitrate: 25_000_000,
+	},
+	"linkedin": {
+		MaxChars: 3000, MaxParts: 20, MaxMediaPerPart: 1, PartsMode: PartsModeCarousel, PartsRequireMedia: true,
+		MediaTypes: []string{"image/jpeg", "image/png", "image/gif", "video/mp4"}, MaxImageSize: 10 << 20, MaxVideoSize: 5 << 30,
+		MinImageAspect: 1 / 1.91, MaxImageAspect: 1.91,
+		MinVideoDuration: 3, MaxVideoDuration: 1800, VideoCodecs: h264Codecs,
+		MaxVideoDimension: 4096, MaxVideoFrameRate: 60,
+	},
+	"instagram": {
+		MaxChars: 2200, MaxParts: 10, MaxMediaPerPart: 1, PartsMode: PartsModeCarousel, PartsRequireMedia: true,
+		MediaTypes: metaMediaTypes, MaxImageSize: 8 << 20, MaxVideoSize: 1 << 30,
+		MinImageAspect: 0.8, MaxImageAspect: 1.91,
+		MinVideoDuration: 3, MaxVideoDuration: 900, VideoCodecs: h264HEVCCodecs,
+		MaxVideoDimension: 1920, MaxVideoFrameRate: 60, MaxVideoBitrate: 25_000_000,
+	},
+	"facebook": {
+		MaxChars: 63206, MaxParts: 0, MaxMediaPerPart: 10, PartsMode: PartsModeNone,
+		MediaTypes: commonMediaTypes, MaxImageSize: 10 << 20, MaxVideoSize: 4 << 30,
+		MaxImageDimension: 2048, MinVideoDuration: 1, MaxVideoDuration: 4 * 60 * 60, VideoCodecs: h264HEVCCodecs,
+	},
+}
+
+// GetPlatformRules returns the limits of a platform and whether it is known
+func GetPlatformRules(platform string) (PlatformRules, bool) {
+	rules, ok := platformRules[platform]
+	return rules, ok
+}
+
+// Platforms returns the names of the known platforms, sorted
+func Platforms() []string {
+	names := make([]string, 0, len(platformRules))
+	for name := range platformRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PostPart is one element of a thread or carousel following the main content of a post
+type PostPart struct {
+	Content     string            `json:"content"`
+	MediaFiles  []Media           `json:"media_files,omitempty"`
+	MediaIDs    []string          `json:"media_ids,omitempty"`    // library media to attach, resolved into MediaFiles
+	PlatformIDs map[string]string `json:"platform_ids,omitempty"` // platform -> published ID
+}
+
+// ValidateForPlatforms checks the post content and its parts against the rules
+// of every target platform. Unknown platforms are not checked.
+func (p *Post) ValidateForPlatforms() error {
+	for _, platform := range p.Platforms {
+		rules, ok := GetPlatformRules(platform)
+		if !ok {
+			continue
+		}
+
+		if n := utf8.RuneCountInString(p.Content); n > rules.MaxChars {
+			return fmt.Errorf("%s: content is %d characters, the limit is %d", platform, n, rules.MaxChars)
+		}
+		for i := range p.Comments {
+			comment := &p.Comments[i]
+			if !containsString(p.CommentPlatforms(comment), platform) {
+				continue
+			}
+			if n := utf8.RuneCountInString(comment.Content); n > rules.MaxChars {
+				return fmt.Errorf("%s: comment %d is %d characters, the limit is %d", platform, i+1, n, rules.MaxChars)
+			}
+		}
+		if err := rules.validateMedia(platform, "", p.MediaFiles); err != nil {
+			return err
+		}
+		if len(p.Parts) == 0 {
+			continue
+		}
+
+		if rules.PartsMode == PartsModeNone {
+			return fmt.Errorf("%s does not support multi-part posts", platform)
+		}
+		// The main content counts as the first part
+		if len(p.Parts)+1 > rules.MaxParts {
+			return fmt.Errorf("%s: %d parts, the limit is %d", platform, len(p.Parts)+1, rules.MaxParts)
+		}
+		for i, part := range p.Parts {
+			if n := utf8.RuneCountInString(part.Content); n > rules.MaxChars {
+				return fmt.Errorf("%s: part %d is %d characters, the limit is %d", platform, i+1, n, rules.MaxChars)
+			}
+			if rules.PartsMode == PartsModeThread && part.Content == "" && len(part.MediaFiles) == 0 {
+				return fmt.Errorf("%s: part %d is empty", platform, i+1)
+			}
+			if rules.PartsRequireMedia && len(part.MediaFiles) == 0 {
+				return fmt.Errorf("%s: part %d needs a media file", platform, i+1)
+			}
+			if len(part.MediaFiles) > rules.MaxMediaPerPart {
+				return fmt.Errorf("%s: part %d has %d media files, the limit is %d", platform, i+1, len(part.MediaFiles), rules.MaxMediaPerPart)
+			}
+			if err := rules.validateMedia(platform, fmt.Sprintf("part %d: ", i+1), part.MediaFiles); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// renderedImageTypes are the library images for which the image pipeline
+// produces renditions that fit every platform
+var renderedImageTypes = []string{"image/jpeg", "image/png"}
+
+// validateMedia checks media files against the formats and sizes a platform
+// accepts, looking at the rendition that will be published. Media uploaded
+// before formats were detected is not checked, and neither are library images
+// whose renditions have been produced.
+func (rules PlatformRules) validateMedia(platform, where string, files []Media) error {
+	for _, media := range files {
+		media = media.ForPlatform(platform)
+		if media.ContentType == "" {
+			continue
+		}
+		if media.Type == "image" && media.ID != "" && media.Processing == MediaProcessingDone && containsString(renderedImageTypes, media.ContentType) {
+			continue
+		}
+		if !containsString(rules.MediaTypes, media.ContentType) {
+			return fmt.Errorf("%s: %s%s is %s, which is not supported", platform, where, media.FileName, media.ContentType)
+		}
+		limit := rules.MaxImageSize
+		if media.Type == "video" {
+			limit = rules.MaxVideoSize
+		}
+		if media.Size > limit {
+			return fmt.Errorf("%s: %s%s is %d MB, the limit for %ss is %d MB", platform, where, media.FileName, media.Size>>20, media.Type, limit>>20)
+		}
+		if media.Video != nil {
+			if err := rules.validateVideo(media); err != nil {
+				return fmt.Errorf("%s: %s%s %v", platform, where, media.FileName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateVideo checks the metadata of a video against the video limits
+func (rules PlatformRules) validateVideo(media Media) error {
+	video := media.Video
+	switch {
+	case rules.MinVideoDuration > 0 && video.DurationSeconds < rules.MinVideoDuration:
+		return fmt.Errorf("is %.1f seconds long, the minimum is %g seconds", video.DurationSeconds, rules.MinVideoDuration)
+	case rules.MaxVideoDuration > 0 && video.DurationSeconds > rules.MaxVideoDuration:
+		return fmt.Errorf("is %.1f seconds long, the limit is %g seconds", video.DurationSeconds, rules.MaxVideoDuration)
+	case len(rules.VideoCodecs) > 0 && !containsString(rules.VideoCodecs, video.VideoCodec):
+		return fmt.Errorf("uses the %s codec, supported codecs are %s", video.VideoCodec, strings.Join(rules.VideoCodecs, ", "))
+	case rules.MaxVideoDimension > 0 && (media.Width > rules.MaxVideoDimension || media.Height > rules.MaxVideoDimension):
+		return fmt.Errorf("is %dx%d, the limit is %d pixels per side", media.Width, media.Height, rules.MaxVideoDimension)
+	case rules.MaxVideoFrameRate > 0 && video.FrameRate > rules.MaxVideoFrameRate+frameRateTolerance:
+		return fmt.Errorf("has %.0f frames per second, the limit is %g", video.FrameRate, rules.MaxVideoFrameRate)
+	case rules.MaxVideoBitrate > 0 && video.Bitrate > rules.MaxVideoBitrate:
+		return fmt.Errorf("has a bitrate of %.1f Mbps, the limit is %g Mbps", float64(video.Bitrate)/1e6, float64(rules.MaxVideoBitrate)/1e6)
+	}
+	return nil
+}
+
+// frameRateTolerance absorbs variable frame rates averaging slightly above
+// their nominal rate
+const frameRateTolerance = 0.5
+
+// MediaForPlatform returns the media files with every image replaced by the
+// version that best suits the platform
+func MediaForPlatform(files []Media, platform string) []Media {
+	if len(files) == 0 {
+		return files
+	}
+	chosen := make([]Media, len(files))
+	for i, media := range files {
+		chosen[i] = media.ForPlatform(platform)
+	}
+	return chosen
+}
+
+// PartsForPlatform applies MediaForPlatform to the media of post parts
+func PartsForPlatform(parts []PostPart, platform string) []PostPart {
+	if len(parts) == 0 {
+		return parts
+	}
+	chosen := make([]PostPart, len(parts))
+	for i, part := range parts {
+		chosen[i] = part
+		chosen[i].MediaFiles = MediaForPlatform(part.MediaFiles, platform)
+	}
+	return chosen
+}
+
+// ForPlatform returns the version of an image to publish to a platform: the
+// original when the platform accepts it, otherwise the first rendition that
+// fits, preferring those closest to the aspect ratio of the original. Videos
+// and images without renditions are returned unchanged.
+func (m Media) ForPlatform(platform string) Media {
+	rules, ok := GetPlatformRules(platform)
+	if !ok || m.Type != "image" || m.Width == 0 || len(m.Renditions) == 0 {
+		return m
+	}
+	if rules.acceptsImage(m.ContentType, m.Width, m.Height, m.Size) {
+		return m
+	}
+
+	candidates := make([]Rendition, 0, len(m.Renditions))
+	for _, r := range m.Renditions {
+		if r.Name != "thumbnail" {
+			candidates = append(candidates, r)
+		}
+	}
+	aspect := float64(m.Width) / float64(m.Height)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return aspectDistance(candidates[i], aspect) < aspectDistance(candidates[j], aspect)
+	})
+
+	for _, r := range candidates {
+		if rules.acceptsImage("image/jpeg", r.Width, r.Height, r.Size) {
+			m.Key, m.URL, m.ContentType = r.Key, r.URL, "image/jpeg"
+			m.Width, m.Height, m.Size = r.Width, r.Height, r.Size
+			return m
+		}
+	}
+	return m
+}
+
+func (rules PlatformRules) acceptsImage(contentType string, width, height int, size int64) bool {
+	aspect := float64(width) / float64(height)
+	switch {
+	case contentType != "" && !containsString(rules.MediaTypes, contentType):
+		return false
+	case rules.MaxImageSize > 0 && size > rules.MaxImageSize:
+		return false
+	case rules.MaxImageDimension > 0 && (width > rules.MaxImageDimension || height > rules.MaxImageDimension):
+		return false
+	case rules.MinImageAspect > 0 && aspect < rules.MinImageAspect-aspectTolerance:
+		return false
+	case rules.MaxImageAspect > 0 && aspect > rules.MaxImageAspect+aspectTolerance:
+		return false
+	}
+	return true
+}
+
+// aspectTolerance absorbs the rounding of rendition dimensions
+const aspectTolerance = 0.01
+
+func aspectDistance(r Rendition, aspect float64) float64 {
+	return math.Abs(math.Log(float64(r.Width) / float64(r.Height) / aspect))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
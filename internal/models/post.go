@@ -2,26 +2,120 @@ package models
 
 import (
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Post struct {
-	ID            string    `json:"id"`
-	Title         string    `json:"title"`
-	Content       string    `json:"content"`
-	Platforms     []string  `json:"platforms"`
-	MediaFiles    []Media   `json:"media_files,omitempty"`
-	Links         []string  `json:"links,omitempty"`
-	ScheduledTime time.Time `json:"scheduled_time,omitempty"`
-	Status        string    `json:"status"` // draft, scheduled, published
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	UserID        string    `json:"user_id"`
+	ID            string             `json:"id"`
+	Title         string             `json:"title"`
+	Content       string             `json:"content"`
+	Platforms     []string           `json:"platforms"`
+	AccountID     string             `json:"account_id,omitempty"`
+	MediaFiles    []Media            `json:"media_files,omitempty"`
+	Parts         []PostPart         `json:"parts,omitempty"` // thread or carousel parts after Content
+	Links         []string           `json:"links,omitempty"`
+	Comments      []FollowUpComment  `json:"comments,omitempty"`        // posted after the post is published
+	NextCommentAt *time.Time         `json:"next_comment_at,omitempty"` // when the earliest pending comment is due
+	Tags          []string           `json:"tags,omitempty"`
+	CampaignID    string             `json:"campaign_id,omitempty"`
+	ScheduledTime time.Time          `json:"scheduled_time,omitempty"`
+	Timezone      string             `json:"timezone,omitempty"` // zone the schedule was entered in
+	Status        string             `json:"status"`             // draft, scheduled, publishing, published, failed, archived
+	StatusHistory []StatusTransition `json:"status_history,omitempty"`
+	PublishedAt   *time.Time         `json:"published_at,omitempty"`
+	PlatformIDs   map[string]string  `json:"platform_ids,omitempty"` // platform -> published ID
+	PublishError  string             `json:"publish_error,omitempty"`
+	Version       int                `json:"version"`
+
+	// Recurring series: the template carries the recurrence, materialized
+	// occurrences point back to it through SeriesID
+	Recurrence     *Recurrence `json:"recurrence,omitempty"`
+	SeriesID       string      `json:"series_id,omitempty"`
+	OccurrenceTime *time.Time  `json:"occurrence_time,omitempty"`
+	Detached       bool        `json:"detached,omitempty"` // occurrence edited on its own
+
+	// SourcePostID is the post this one was duplicated from
+	SourcePostID string `json:"source_post_id,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	UserID    string     `json:"user_id"`
+
+	// SeqNo and PrimaryTerm identify the stored version of the document for
+	// optimistic concurrency control. They are never persisted in the source.
+	SeqNo       int `json:"-"`
+	PrimaryTerm int `json:"-"`
+}
+
+// InLocation returns a copy of the post with its timestamps rendered in loc
+func (p Post) InLocation(loc *time.Location) Post {
+	if !p.ScheduledTime.IsZero() {
+		p.ScheduledTime = p.ScheduledTime.In(loc)
+	}
+	p.CreatedAt = p.CreatedAt.In(loc)
+	p.UpdatedAt = p.UpdatedAt.In(loc)
+	for _, t := range []**time.Time{&p.PublishedAt, &p.DeletedAt, &p.OccurrenceTime} {
+		if *t != nil {
+			local := (*t).In(loc)
+			*t = &local
+		}
+	}
+	return p
+}
+
+// Duplicate returns an unsaved draft copy of the post. Media files are shared
+// with the original rather than copied; publishing state, schedule and series
+// membership are left behind.
+func (p *Post) Duplicate() (*Post, error) {
+	duplicate := &Post{
+		Title:        p.Title,
+		Content:      p.Content,
+		Platforms:    append([]string(nil), p.Platforms...),
+		AccountID:    p.AccountID,
+		MediaFiles:   append([]Media(nil), p.MediaFiles...),
+		Parts:        make([]PostPart, len(p.Parts)),
+		Links:        append([]string(nil), p.Links...),
+		Tags:         append([]string(nil), p.Tags...),
+		CampaignID:   p.CampaignID,
+		UserID:       p.UserID,
+		SourcePostID: p.ID,
+	}
+	for i, part := range p.Parts {
+		duplicate.Parts[i] = PostPart{Content: part.Content, MediaFiles: part.MediaFiles}
+	}
+	for _, comment := range p.Comments {
+		duplicate.Comments = append(duplicate.Comments, FollowUpComment{
+			ID:            uuid.New().String(),
+			Content:       comment.Content,
+			OffsetSeconds: comment.OffsetSeconds,
+			Platforms:     comment.Platforms,
+			Status:        CommentStatusPending,
+		})
+	}
+	if err := duplicate.TransitionTo(PostStatusDraft); err != nil {
+		return nil, err
+	}
+	return duplicate, nil
 }
 
+// Media is a file attached to a post. ID refers to the asset in the media
+// library; entries without one predate the library.
 type Media struct {
-	URL      string `json:"url"`
-	Type     string `json:"type"` // image, video
-	FileName string `json:"file_name"`
+	ID          string      `json:"id,omitempty"`
+	Key         string      `json:"key,omitempty"` // storage key, empty for files saved before storage backends
+	URL         string      `json:"url"`
+	Type        string      `json:"type"` // image, video
+	FileName    string      `json:"file_name"`
+	ContentType string      `json:"content_type,omitempty"` // detected from the content, empty for legacy media
+	Size        int64       `json:"size,omitempty"`
+	Width       int         `json:"width,omitempty"`
+	Height      int         `json:"height,omitempty"`
+	Renditions  []Rendition `json:"renditions,omitempty"`
+	Video       *VideoInfo  `json:"video,omitempty"`    // empty for videos uploaded before metadata was read
+	AltText     string      `json:"alt_text,omitempty"` // copied from the asset when publishing
+	Processing  string      `json:"-"`                  // image pipeline state of the asset, not stored
 }
 
 type CreatePostRequest struct {
@@ -30,14 +124,43 @@ type CreatePostRequest struct {
 	Platforms     []string  `json:"platforms" binding:"required"`
 	Links         []string  `json:"links"`
 	ScheduledTime time.Time `json:"scheduled_time"`
-	Status        string    `json:"status" binding:"required,oneof=draft scheduled published"`
+	Status        string    `json:"status" binding:"required,oneof=draft scheduled"`
 }
 
 type UpdatePostRequest struct {
-	Title         string    `json:"title"`
-	Content       string    `json:"content"`
-	Platforms     []string  `json:"platforms"`
-	Links         []string  `json:"links"`
-	ScheduledTime time.Time `json:"scheduled_time"`
-	Status        string    `json:"status" binding:"oneof=draft scheduled published"`
+	Title         string                   `json:"title"`
+	Content       string                   `json:"content"`
+	Platforms     []string                 `json:"platforms"`
+	Links         []string                 `json:"links"`
+	MediaIDs      []string                 `json:"media_ids"` // replaces the post's own media with library media
+	Parts         []PostPart               `json:"parts"`
+	Comments      []FollowUpCommentRequest `json:"comments"`
+	Tags          []string                 `json:"tags"`
+	CampaignID    *string                  `json:"campaign_id"` // empty string detaches the campaign
+	ScheduledTime time.Time                `json:"scheduled_time"`
+	Status        string                   `json:"status" binding:"omitempty,oneof=draft scheduled archived"`
+
+	// LocalScheduledTime is a wall-clock time without offset, e.g. 2024-03-31T09:00,
+	// interpreted in Timezone or the user's preferred timezone. DST resolves
+	// skipped or repeated times: reject (default), earlier, later or compatible.
+	LocalScheduledTime string `json:"local_scheduled_time"`
+	Timezone           string `json:"timezone"`
+	DST                string `json:"dst" binding:"omitempty,oneof=reject earlier later compatible"`
+}
+
+// ChangesContent reports whether the request edits anything besides the status
+func (r *UpdatePostRequest) ChangesContent() bool {
+	return r.Title != "" || r.Content != "" || len(r.Platforms) > 0 || r.Links != nil ||
+		r.MediaIDs != nil || r.Parts != nil || r.Comments != nil || r.Tags != nil ||
+		r.CampaignID != nil || !r.ScheduledTime.IsZero() || r.LocalScheduledTime != ""
+}
+
+// DuplicatePostRequest optionally retargets the copy of a post. The schedule is
+// given either as ScheduledTime or as LocalScheduledTime, like in UpdatePostRequest.
+type DuplicatePostRequest struct {
+	Platforms          []string  `json:"platforms"`
+	ScheduledTime      time.Time `json:"scheduled_time"`
+	LocalScheduledTime string    `json:"local_scheduled_time"`
+	Timezone           string    `json:"timezone"`
+	DST                string    `json:"dst" binding:"omitempty,oneof=reject earlier later compatible"`
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Post statuses
+const (
+	PostStatusDraft      = "draft"
+	PostStatusScheduled  = "scheduled"
+	PostStatusPublishing = "publishing"
+	PostStatusPublished  = "published"
+	PostStatusFailed     = "failed"
+	PostStatusArchived   = "archived"
+)
+
+// ErrInvalidStatusTransition is returned when a post is moved to a status
+// that is not reachable from its current one
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// postStatusTransitions lists the statuses reachable from each status.
+// The empty status is the initial state of a post that has not been saved yet.
+var postStatusTransitions = map[string][]string{
+	"":                   {PostStatusDraft, PostStatusScheduled},
+	PostStatusDraft:      {PostStatusScheduled, PostStatusArchived},
+	PostStatusScheduled:  {PostStatusDraft, PostStatusPublishing, PostStatusArchived},
+	PostStatusPublishing: {PostStatusPublished, PostStatusFailed},
+	PostStatusFailed:     {PostStatusDraft, PostStatusScheduled, PostStatusArchived},
+	PostStatusPublished:  {PostStatusArchived},
+	PostStatusArchived:   {PostStatusDraft},
+}
+
+// StatusTransition records a single status change of a post
+type StatusTransition struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// CanTransition reports whether a post may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range postStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the post to the given status, recording the transition
+// in its history. Illegal moves return ErrInvalidStatusTransition.
+func (p *Post) TransitionTo(status string) error {
+	if !CanTransition(p.Status, status) {
+		return fmt.Errorf("%w: %q to %q", ErrInvalidStatusTransition, p.Status, status)
+	}
+
+	now := time.Now()
+	p.StatusHistory = append(p.StatusHistory, StatusTransition{
+		From: p.Status,
+		To:   status,
+		At:   now,
+	})
+	p.Status = status
+
+	if status == PostStatusPublished {
+		p.PublishedAt = &now
+	}
+	return nil
+}
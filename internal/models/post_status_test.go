@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransitionTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr bool
+	}{
+		{"new post to draft", "", PostStatusDraft, false},
+		{"new post to scheduled", "", PostStatusScheduled, false},
+		{"new post to published", "", PostStatusPublished, true},
+		{"draft to scheduled", PostStatusDraft, PostStatusScheduled, false},
+		{"draft to publishing", PostStatusDraft, PostStatusPublishing, true},
+		{"scheduled to publishing", PostStatusScheduled, PostStatusPublishing, false},
+		{"publishing to published", PostStatusPublishing, PostStatusPublished, false},
+		{"publishing to failed", PostStatusPublishing, PostStatusFailed, false},
+		{"publishing to draft", PostStatusPublishing, PostStatusDraft, true},
+		{"failed to scheduled", PostStatusFailed, PostStatusScheduled, false},
+		{"published to archived", PostStatusPublished, PostStatusArchived, false},
+		{"published to scheduled", PostStatusPublished, PostStatusScheduled, true},
+		{"archived to draft", PostStatusArchived, PostStatusDraft, false},
+		{"unknown status", "deleted", PostStatusDraft, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &Post{Status: tt.from}
+			err := post.TransitionTo(tt.to)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidStatusTransition) {
+					t.Fatalf("TransitionTo(%q) error = %v, want ErrInvalidStatusTransition", tt.to, err)
+				}
+				if post.Status != tt.from || len(post.StatusHistory) != 0 {
+					t.Errorf("rejected transition changed the post: status %q, history %v", post.Status, post.StatusHistory)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TransitionTo(%q) error = %v", tt.to, err)
+			}
+			if post.Status != tt.to {
+				t.Errorf("status = %q, want %q", post.Status, tt.to)
+			}
+			if len(post.StatusHistory) != 1 || post.StatusHistory[0].From != tt.from || post.StatusHistory[0].To != tt.to {
+				t.Errorf("history = %v, want a single %q to %q transition", post.StatusHistory, tt.from, tt.to)
+			}
+			if (post.PublishedAt != nil) != (tt.to == PostStatusPublished) {
+				t.Errorf("published_at = %v after moving to %q", post.PublishedAt, tt.to)
+			}
+		})
+	}
+}
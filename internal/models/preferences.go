@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// UserPreferences holds per-user settings such as the timezone used to
+// interpret and render scheduled times. There are no workspaces to hold a
+// shared default, so users without a preferred timezone use UTC.
+type UserPreferences struct {
+	UserID    string    `json:"user_id"`
+	Timezone  string    `json:"timezone"` // IANA zone, e.g. Europe/Berlin
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PreferencesRequest struct {
+	Timezone string `json:"timezone" binding:"required"`
+}
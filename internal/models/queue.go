@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PostingQueue holds the weekly posting slots of a social account and the
+// ordered posts waiting to be published in them
+type PostingQueue struct {
+	AccountID string      `json:"account_id"`
+	UserID    string      `json:"user_id"`
+	Timezone  string      `json:"timezone"`
+	Slots     []QueueSlot `json:"slots"`
+	PostIDs   []string    `json:"post_ids"`
+	Paused    bool        `json:"paused"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	DeletedAt *time.Time  `json:"deleted_at,omitempty"`
+
+	// SeqNo and PrimaryTerm identify the stored version of the queue, as for posts
+	SeqNo       int `json:"-"`
+	PrimaryTerm int `json:"-"`
+}
+
+// QueueSlot is a weekly posting time, e.g. Monday at 09:00, in the queue's timezone
+type QueueSlot struct {
+	Weekday time.Weekday `json:"weekday"` // 0 = Sunday
+	Time    string       `json:"time"`    // HH:MM
+}
+
+type QueueScheduleRequest struct {
+	Timezone string      `json:"timezone" binding:"required"`
+	Slots    []QueueSlot `json:"slots" binding:"required,min=1"`
+}
+
+type QueuePostRequest struct {
+	PostID string `json:"post_id" binding:"required"`
+}
+
+type QueueOrderRequest struct {
+	PostIDs []string `json:"post_ids" binding:"required"`
+}
+
+// Validate checks the timezone and every slot of the queue
+func (q *PostingQueue) Validate() error {
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", q.Timezone, err)
+	}
+	if len(q.Slots) == 0 {
+		return fmt.Errorf("at least one slot is required")
+	}
+	seen := make(map[QueueSlot]bool)
+	for _, slot := range q.Slots {
+		if seen[slot] {
+			return fmt.Errorf("duplicate slot %d %s", slot.Weekday, slot.Time)
+		}
+		seen[slot] = true
+		if slot.Weekday < time.Sunday || slot.Weekday > time.Saturday {
+			return fmt.Errorf("invalid weekday %d", slot.Weekday)
+		}
+		if _, err := time.Parse("15:04", slot.Time); err != nil {
+			return fmt.Errorf("invalid slot time %q, expected HH:MM", slot.Time)
+		}
+	}
+	return nil
+}
+
+// NextSlots returns the next n free slot times strictly after the given time,
+// in UTC. Slots at an occupied time, taken by another post of the account,
+// are skipped.
+func (q *PostingQueue) NextSlots(after time.Time, n int, occupied []time.Time) ([]time.Time, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	loc, _ := time.LoadLocation(q.Timezone)
+	taken := make(map[int64]bool, len(occupied))
+	for _, t := range occupied {
+		taken[t.Unix()] = true
+	}
+
+	local := after.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	var slots []time.Time
+	for len(slots) < n {
+		var today []time.Time
+		for _, slot := range q.Slots {
+			if day.Weekday() != slot.Weekday {
+				continue
+			}
+			clock, _ := time.Parse("15:04", slot.Time)
+			t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+			if t.After(after) && !taken[t.Unix()] {
+				today = append(today, t.UTC())
+			}
+		}
+		sort.Slice(today, func(i, j int) bool { return today[i].Before(today[j]) })
+		slots = append(slots, today...)
+		day = day.AddDate(0, 0, 1)
+	}
+
+	return slots[:n], nil
+}
+
+// Contains reports whether the post is in the queue
+func (q *PostingQueue) Contains(postID string) bool {
+	for _, id := range q.PostIDs {
+		if id == postID {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove takes a post out of the queue
+func (q *PostingQueue) Remove(postID string) {
+	ids := q.PostIDs[:0]
+	for _, id := range q.PostIDs {
+		if id != postID {
+			ids = append(ids, id)
+		}
+	}
+	q.PostIDs = ids
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNextSlots(t *testing.T) {
+	monday := time.Date(2024, 3, 4, 8, 30, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time { return time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		queue    PostingQueue
+		occupied []time.Time
+		n        int
+		want     []time.Time
+	}{
+		{
+			name: "weekly slots in order",
+			queue: PostingQueue{Timezone: "UTC", Slots: []QueueSlot{
+				{Weekday: time.Tuesday, Time: "17:00"},
+				{Weekday: time.Monday, Time: "09:00"},
+				{Weekday: time.Monday, Time: "08:00"},
+			}},
+			n:    4,
+			want: []time.Time{at(4, 9), at(5, 17), at(11, 8), at(11, 9)},
+		},
+		{
+			name:     "occupied slots skipped",
+			queue:    PostingQueue{Timezone: "UTC", Slots: []QueueSlot{{Weekday: time.Monday, Time: "09:00"}}},
+			occupied: []time.Time{at(4, 9), at(11, 9).In(time.FixedZone("CET", 3600))},
+			n:        2,
+			want:     []time.Time{at(18, 9), at(25, 9)},
+		},
+		{
+			name:     "other times do not occupy a slot",
+			queue:    PostingQueue{Timezone: "UTC", Slots: []QueueSlot{{Weekday: time.Monday, Time: "09:00"}}},
+			occupied: []time.Time{at(4, 10)},
+			n:        1,
+			want:     []time.Time{at(4, 9)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.queue.NextSlots(monday, tt.n, tt.occupied)
+			if err != nil {
+				t.Fatalf("NextSlots() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NextSlots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
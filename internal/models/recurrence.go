@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/teambition/rrule-go"
+)
+
+// Recurrence turns a post into the template of a recurring series.
+// Occurrences are computed from an iCalendar RRULE evaluated in Timezone, so
+// wall-clock times stay stable across DST changes.
+type Recurrence struct {
+	RRule             string      `json:"rrule"`    // e.g. FREQ=WEEKLY;BYDAY=TU
+	Timezone          string      `json:"timezone"` // IANA zone, e.g. Europe/Berlin
+	Start             time.Time   `json:"start"`
+	Exceptions        []time.Time `json:"exceptions,omitempty"` // skipped occurrences
+	MaterializedUntil *time.Time  `json:"materialized_until,omitempty"`
+	EndedAt           *time.Time  `json:"ended_at,omitempty"`
+}
+
+type RecurrenceRequest struct {
+	RRule    string    `json:"rrule" binding:"required"`
+	Timezone string    `json:"timezone" binding:"required"`
+	Start    time.Time `json:"start"`
+}
+
+type SkipOccurrenceRequest struct {
+	OccurrenceTime time.Time `json:"occurrence_time" binding:"required"`
+}
+
+// rule parses the RRULE anchored at Start in the series timezone
+func (r *Recurrence) rule() (*rrule.RRule, error) {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %v", r.Timezone, err)
+	}
+
+	opt, err := rrule.StrToROptionInLocation(r.RRule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rrule: %v", err)
+	}
+	opt.Dtstart = r.Start.In(loc)
+
+	return rrule.NewRRule(*opt)
+}
+
+// Validate checks that the rule and timezone can be evaluated
+func (r *Recurrence) Validate() error {
+	if r.Start.IsZero() {
+		return fmt.Errorf("recurrence start is required")
+	}
+	_, err := r.rule()
+	return err
+}
+
+// Between returns the occurrences in (after, before], leaving out skipped
+// occurrences and anything after the series was ended
+func (r *Recurrence) Between(after, before time.Time) ([]time.Time, error) {
+	rule, err := r.rule()
+	if err != nil {
+		return nil, err
+	}
+	if r.EndedAt != nil && r.EndedAt.Before(before) {
+		before = *r.EndedAt
+	}
+	if !before.After(after) {
+		return nil, nil
+	}
+
+	var occurrences []time.Time
+	for _, t := range rule.Between(after, before, true) {
+		if t.Equal(after) || r.IsSkipped(t) {
+			continue
+		}
+		occurrences = append(occurrences, t.UTC())
+	}
+	return occurrences, nil
+}
+
+// IsSkipped reports whether the occurrence at t has been skipped
+func (r *Recurrence) IsSkipped(t time.Time) bool {
+	for _, ex := range r.Exceptions {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Occurs reports whether t is an occurrence of the series
+func (r *Recurrence) Occurs(t time.Time) (bool, error) {
+	rule, err := r.rule()
+	if err != nil {
+		return false, err
+	}
+	return rule.After(t, true).Equal(t), nil
+}
+
+// NewOccurrence creates the scheduled post for a single occurrence of the series
+func (p *Post) NewOccurrence(at time.Time) (*Post, error) {
+	occurrence := &Post{
+		ScheduledTime:  at,
+		UserID:         p.UserID,
+		SeriesID:       p.ID,
+		OccurrenceTime: &at,
+	}
+	p.ApplyToOccurrence(occurrence)
+	if err := occurrence.TransitionTo(PostStatusScheduled); err != nil {
+		return nil, err
+	}
+	return occurrence, nil
+}
+
+// ApplyToOccurrence copies the content of the series template into one of
+// its occurrences, replacing what the occurrence had
+func (p *Post) ApplyToOccurrence(occurrence *Post) {
+	occurrence.Title = p.Title
+	occurrence.Content = p.Content
+	occurrence.Platforms = p.Platforms
+	occurrence.MediaFiles = p.MediaFiles
+	occurrence.Links = p.Links
+	occurrence.Parts = make([]PostPart, len(p.Parts))
+	for i, part := range p.Parts {
+		occurrence.Parts[i] = PostPart{Content: part.Content, MediaFiles: part.MediaFiles}
+	}
+	occurrence.Comments = nil
+	for _, comment := range p.Comments {
+		occurrence.Comments = append(occurrence.Comments, FollowUpComment{
+			ID:            uuid.New().String(),
+			Content:       comment.Content,
+			OffsetSeconds: comment.OffsetSeconds,
+			Platforms:     comment.Platforms,
+			Status:        CommentStatusPending,
+		})
+	}
+}
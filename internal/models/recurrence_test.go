@@ -0,0 +1,61 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecurrenceBetween(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC) }
+	ended := day(13, 0)
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		after      time.Time
+		before     time.Time
+		want       []time.Time
+	}{
+		{
+			name:       "after is exclusive",
+			recurrence: Recurrence{RRule: "FREQ=DAILY", Timezone: "UTC", Start: day(1, 9)},
+			after:      day(1, 9),
+			before:     day(3, 0),
+			want:       []time.Time{day(2, 9)},
+		},
+		{
+			name:       "wall clock kept across DST",
+			recurrence: Recurrence{RRule: "FREQ=DAILY", Timezone: "Europe/Berlin", Start: day(30, 8)},
+			after:      day(30, 0),
+			before:     day(31, 23),
+			want:       []time.Time{day(30, 8), day(31, 7)},
+		},
+		{
+			name:       "skipped occurrences left out",
+			recurrence: Recurrence{RRule: "FREQ=DAILY", Timezone: "UTC", Start: day(1, 9), Exceptions: []time.Time{day(2, 9)}},
+			after:      day(1, 0),
+			before:     day(3, 23),
+			want:       []time.Time{day(1, 9), day(3, 9)},
+		},
+		{
+			name:       "nothing after the series ended",
+			recurrence: Recurrence{RRule: "FREQ=WEEKLY", Timezone: "UTC", Start: day(5, 9), EndedAt: &ended},
+			after:      day(1, 0),
+			before:     day(31, 0),
+			want:       []time.Time{day(5, 9), day(12, 9)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.recurrence.Between(tt.after, tt.before)
+			if err != nil {
+				t.Fatalf("Between() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// PostRevision is an immutable snapshot of a post written on every change
+type PostRevision struct {
+	ID        string                 `json:"id"`
+	PostID    string                 `json:"post_id"`
+	Version   int                    `json:"version"`
+	EditorID  string                 `json:"editor_id"`
+	Changes   map[string]FieldChange `json:"changes,omitempty"`
+	Snapshot  Post                   `json:"snapshot"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// FieldChange holds the old and new value of a single post field
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// revisionIgnoredFields are bookkeeping fields left out of revision diffs
+var revisionIgnoredFields = map[string]bool{
+	"version":    true,
+	"updated_at": true,
+}
+
+// DiffPosts returns the fields that differ between two versions of a post,
+// keyed by their JSON name
+func DiffPosts(oldPost, newPost *Post) map[string]FieldChange {
+	oldFields := postFields(oldPost)
+	newFields := postFields(newPost)
+
+	changes := make(map[string]FieldChange)
+	for name, newValue := range newFields {
+		if revisionIgnoredFields[name] {
+			continue
+		}
+		if oldValue := oldFields[name]; !reflect.DeepEqual(oldValue, newValue) {
+			changes[name] = FieldChange{Old: oldValue, New: newValue}
+		}
+	}
+	for name, oldValue := range oldFields {
+		if _, ok := newFields[name]; !ok && !revisionIgnoredFields[name] {
+			changes[name] = FieldChange{Old: oldValue, New: nil}
+		}
+	}
+	return changes
+}
+
+// RestoreRevision copies the editable content of a revision back onto the post.
+// Status is left untouched so that it keeps going through the state machine.
+func (p *Post) RestoreRevision(rev *PostRevision) {
+	p.Title = rev.Snapshot.Title
+	p.Content = rev.Snapshot.Content
+	p.Platforms = rev.Snapshot.Platforms
+	p.AccountID = rev.Snapshot.AccountID
+	p.MediaFiles = rev.Snapshot.MediaFiles
+	p.Parts = rev.Snapshot.Parts
+	p.Links = rev.Snapshot.Links
+	p.Comments = rev.Snapshot.Comments
+	p.Tags = rev.Snapshot.Tags
+	p.CampaignID = rev.Snapshot.CampaignID
+	p.ScheduledTime = rev.Snapshot.ScheduledTime
+	p.Timezone = rev.Snapshot.Timezone
+}
+
+// MediaIDs returns the IDs of the library media used by the post and its parts
+func (p *Post) MediaIDs() []string {
+	var ids []string
+	for _, media := range p.MediaFiles {
+		if media.ID != "" {
+			ids = append(ids, media.ID)
+		}
+	}
+	for _, part := range p.Parts {
+		for _, media := range part.MediaFiles {
+			if media.ID != "" {
+				ids = append(ids, media.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// postFields flattens a post into its JSON field values
+func postFields(p *Post) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if p == nil {
+		return fields
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Storage plans
+const (
+	StoragePlanFree     = "free"
+	StoragePlanPro      = "pro"
+	StoragePlanBusiness = "business"
+
+	DefaultStoragePlan = StoragePlanFree
+)
+
+// ErrStorageQuotaExceeded is returned for uploads that do not fit the quota of a user
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+// StorageQuota limits the media library of a user, zero is unlimited
+type StorageQuota struct {
+	MaxBytes int64 `json:"max_bytes" binding:"min=0"`
+	MaxFiles int   `json:"max_files" binding:"min=0"`
+}
+
+var storageQuotas = map[string]StorageQuota{
+	StoragePlanFree:     {MaxBytes: 2 << 30, MaxFiles: 500},
+	StoragePlanPro:      {MaxBytes: 100 << 30, MaxFiles: 10000},
+	StoragePlanBusiness: {MaxBytes: 1 << 40, MaxFiles: 100000},
+}
+
+// GetStorageQuota returns the quota of a plan and whether the plan is known
+func GetStorageQuota(plan string) (StorageQuota, bool) {
+	quota, ok := storageQuotas[plan]
+	return quota, ok
+}
+
+// StorageUsage is the size and number of files in the media library of a
+// user. It is updated as assets are added and removed; renditions generated
+// by the image pipeline are not counted.
+type StorageUsage struct {
+	UserID string `json:"user_id"`
+	Plan   string `json:"plan"`
+	Bytes  int64  `json:"bytes"`
+	Files  int    `json:"files"`
+	// Quota replaces the quota of the plan for this user when set
+	Quota     *StorageQuota `json:"quota,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// Limits returns the quota that applies to the user
+func (u *StorageUsage) Limits() StorageQuota {
+	if u.Quota != nil {
+		return *u.Quota
+	}
+	if quota, ok := GetStorageQuota(u.Plan); ok {
+		return quota
+	}
+	quota, _ := GetStorageQuota(DefaultStoragePlan)
+	return quota
+}
+
+// CheckUpload reports whether a new file of size bytes fits the quota
+func (u *StorageUsage) CheckUpload(size int64) error {
+	limits := u.Limits()
+	allowance := u.Plan + " plan"
+	if u.Quota != nil {
+		allowance = "storage quota"
+	}
+	if limits.MaxFiles > 0 && u.Files+1 > limits.MaxFiles {
+		return fmt.Errorf("%w: your %s includes %d files and %d are stored; delete unused media or upgrade your plan",
+			ErrStorageQuotaExceeded, allowance, limits.MaxFiles, u.Files)
+	}
+	if limits.MaxBytes > 0 && u.Bytes+size > limits.MaxBytes {
+		return fmt.Errorf("%w: the file is %s but only %s of the %s in your %s are left; delete unused media or upgrade your plan",
+			ErrStorageQuotaExceeded, formatBytes(size), formatBytes(max(limits.MaxBytes-u.Bytes, 0)), formatBytes(limits.MaxBytes), allowance)
+	}
+	return nil
+}
+
+// UpdateStorageQuotaRequest changes the plan of a user and optionally
+// overrides its quota. A missing quota reverts to the quota of the plan.
+type UpdateStorageQuotaRequest struct {
+	Plan  string        `json:"plan" binding:"required"`
+	Quota *StorageQuota `json:"quota"`
+}
+
+// formatBytes renders a size in binary units, e.g. 1.5 GB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[exp])
+}
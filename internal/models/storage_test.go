@@ -0,0 +1,29 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStorageUsageCheckUpload(t *testing.T) {
+	free, _ := GetStorageQuota(StoragePlanFree)
+
+	tests := []struct {
+		name    string
+		usage   StorageUsage
+		size    int64
+		wantErr bool
+	}{
+		{"fills the quota exactly", StorageUsage{Plan: StoragePlanFree, Bytes: free.MaxBytes - 100}, 100, false},
+		{"over the byte quota", StorageUsage{Plan: StoragePlanFree, Bytes: free.MaxBytes - 100}, 101, true},
+		{"over the file quota", StorageUsage{Plan: StoragePlanFree, Files: free.MaxFiles}, 1, true},
+		{"quota overrides the plan", StorageUsage{Plan: StoragePlanFree, Files: free.MaxFiles, Quota: &StorageQuota{MaxFiles: free.MaxFiles + 1}}, 1, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.usage.CheckUpload(tt.size)
+		if tt.wantErr != errors.Is(err, ErrStorageQuotaExceeded) {
+			t.Errorf("%s: CheckUpload(%d) error = %v, wantErr %v", tt.name, tt.size, err, tt.wantErr)
+		}
+	}
+}
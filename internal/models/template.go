@@ -0,0 +1,146 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PostTemplate is a reusable post with {{variable}} placeholders and {{snippet:name}} references
+type PostTemplate struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Platforms []string   `json:"platforms,omitempty"`
+	Links     []string   `json:"links,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Variables []string   `json:"variables,omitempty"` // placeholders used by the template itself
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Snippet is a reusable block of text such as a disclaimer or a hashtag set
+type Snippet struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	Name      string     `json:"name"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type TemplateRequest struct {
+	Name      string   `json:"name" binding:"required"`
+	Title     string   `json:"title" binding:"required"`
+	Content   string   `json:"content" binding:"required"`
+	Platforms []string `json:"platforms"`
+	Links     []string `json:"links"`
+	Tags      []string `json:"tags"`
+}
+
+type SnippetRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Content string `json:"content" binding:"required"`
+}
+
+type RenderTemplateRequest struct {
+	Variables map[string]string `json:"variables"`
+	Platforms []string          `json:"platforms"`
+}
+
+// placeholderPattern matches {{name}} and {{snippet:name}}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(snippet:)?([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// snippetNamePattern restricts snippet names to what a placeholder can reference
+var snippetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// ValidSnippetName reports whether a snippet can be referenced under this name
+func ValidSnippetName(name string) bool {
+	return snippetNamePattern.MatchString(name)
+}
+
+// TemplateVariables returns the sorted variable names used in the given texts,
+// ignoring snippet references
+func TemplateVariables(texts ...string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, text := range texts {
+		for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+			if m[1] != "" || seen[m[2]] {
+				continue
+			}
+			seen[m[2]] = true
+			names = append(names, m[2])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render expands the snippets and variables of the template into a draft post.
+// Every variable must be provided, otherwise an error naming the missing ones is returned.
+func (t *PostTemplate) Render(variables map[string]string, snippets map[string]string) (*Post, error) {
+	var unknown []string
+	expand := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := placeholderPattern.FindStringSubmatch(match)
+			if m[1] == "" {
+				return match
+			}
+			content, ok := snippets[m[2]]
+			if !ok {
+				unknown = append(unknown, m[2])
+				return match
+			}
+			return content
+		})
+	}
+	title := expand(t.Title)
+	content := expand(t.Content)
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown snippets: %s", strings.Join(unknown, ", "))
+	}
+
+	var missing []string
+	for _, name := range TemplateVariables(append([]string{title, content}, t.Links...)...) {
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing variables: %s", strings.Join(missing, ", "))
+	}
+
+	substitute := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := placeholderPattern.FindStringSubmatch(match)
+			if m[1] != "" {
+				return match
+			}
+			return variables[m[2]]
+		})
+	}
+
+	links := make([]string, len(t.Links))
+	for i, link := range t.Links {
+		links[i] = substitute(link)
+	}
+
+	post := &Post{
+		Title:     substitute(title),
+		Content:   substitute(content),
+		Platforms: t.Platforms,
+		Links:     links,
+		Tags:      t.Tags,
+	}
+	if err := post.TransitionTo(PostStatusDraft); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
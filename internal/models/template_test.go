@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestTemplateRender(t *testing.T) {
+	snippets := map[string]string{"hashtags": "#launch #{{product}}"}
+
+	tests := []struct {
+		name      string
+		template  PostTemplate
+		variables map[string]string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "snippets expanded before variables",
+			template:  PostTemplate{Title: "New", Content: "Meet {{ product }} {{snippet:hashtags}}"},
+			variables: map[string]string{"product": "widget"},
+			want:      "Meet widget #launch #widget",
+		},
+		{
+			name:      "values are not expanded",
+			template:  PostTemplate{Title: "New", Content: "{{name}}"},
+			variables: map[string]string{"name": "{{snippet:hashtags}}"},
+			want:      "{{snippet:hashtags}}",
+		},
+		{
+			name:     "missing variables named",
+			template: PostTemplate{Title: "{{product}}", Content: "{{company}}"},
+			wantErr:  "missing variables: company, product",
+		},
+		{
+			name:     "unknown snippets named",
+			template: PostTemplate{Title: "New", Content: "{{snippet:footer}}"},
+			wantErr:  "unknown snippets: footer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := tt.template.Render(tt.variables, snippets)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if post.Content != tt.want || post.Status != PostStatusDraft {
+				t.Errorf("Render() = %q (%s), want %q (draft)", post.Content, post.Status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// Request is a single publish call to a social platform
+type Request struct {
+	AccountID string
+	Content   string
+	Media     []models.Media
+	Links     []string
+	// ReplyToID is the platform ID of the previous part when publishing a thread
+	ReplyToID string
+	// Parts holds the slides of a carousel, published together with Content
+	Parts []models.PostPart
+	// CommentOnID is the platform ID of a post to comment on instead of creating a new post
+	CommentOnID string
+}
+
+// Client publishes content to one social platform and returns the platform ID of the created post
+type Client interface {
+	Publish(ctx context.Context, req Request) (string, error)
+}
+
+// Registry maps platform names to their clients
+type Registry struct {
+	clients map[string]Client
+}
+
+func NewRegistry() *Registry {
+	return &Registry{clients: make(map[string]Client)}
+}
+
+// Register sets the client used for a platform
+func (r *Registry) Register(platform string, client Client) {
+	r.clients[platform] = client
+}
+
+// Get returns the client of a platform
+func (r *Registry) Get(platform string) (Client, error) {
+	client, ok := r.clients[platform]
+	if !ok {
+		return nil, fmt.Errorf("no publisher configured for platform %q", platform)
+	}
+	return client, nil
+}
+
+// Supports reports whether a client is registered for every platform
+func (r *Registry) Supports(platforms []string) bool {
+	for _, platform := range platforms {
+		if _, ok := r.clients[platform]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of registered platforms
+func (r *Registry) Len() int {
+	return len(r.clients)
+}
@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// GetUserTags counts the posts of a user per tag
+func (es *ElasticsearchDB) GetUserTags(userID string) ([]models.TagCount, error) {
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter":   map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+				"must_not": notDeleted,
+			},
+		},
+		"aggs": map[string]interface{}{
+			"tags": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "tags", "size": 1000},
+			},
+		},
+	}
+
+	res, err := es.client.Search(
+		es.client.Search.WithIndex("posts"),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting tags: %s", res.String())
+	}
+
+	var result struct {
+		Aggregations struct {
+			Tags struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int    `json:"doc_count"`
+				} `json:"buckets"`
+			} `json:"tags"`
+		} `json:"aggregations"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	tags := make([]models.TagCount, len(result.Aggregations.Tags.Buckets))
+	for i, b := range result.Aggregations.Tags.Buckets {
+		tags[i] = models.TagCount{Tag: b.Key, Count: b.DocCount}
+	}
+	return tags, nil
+}
+
+// postAnalytics holds the summed analytics of a single post
+type postAnalytics struct {
+	Likes      int
+	Comments   int
+	Shares     int
+	Reach      int
+	Engagement float64
+}
+
+// getPostAnalytics sums the analytics records of the given posts, keyed by post ID
+func (es *ElasticsearchDB) getPostAnalytics(postIDs []string) (map[string]postAnalytics, error) {
+	if len(postIDs) == 0 {
+		return map[string]postAnalytics{}, nil
+	}
+
+	sum := func(field string) map[string]interface{} {
+		return map[string]interface{}{"sum": map[string]interface{}{"field": field}}
+	}
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"terms": map[string]interface{}{"post_id": postIDs},
+		},
+		"aggs": map[string]interface{}{
+			"posts": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "post_id", "size": len(postIDs)},
+				"aggs": map[string]interface{}{
+					"likes":      sum("likes"),
+					"comments":   sum("comments"),
+					"shares":     sum("shares"),
+					"reach":      sum("reach"),
+					"engagement": map[string]interface{}{"avg": map[string]interface{}{"field": "engagement"}},
+				},
+			},
+		},
+	}
+
+	if err := es.createIndexIfNotExists("analytics"); err != nil {
+		return nil, err
+	}
+	res, err := es.client.Search(
+		es.client.Search.WithIndex("analytics"),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting analytics: %s", res.String())
+	}
+
+	type metric struct {
+		Value float64 `json:"value"`
+	}
+	var result struct {
+		Aggregations struct {
+			Posts struct {
+				Buckets []struct {
+					Key        string `json:"key"`
+					Likes      metric `json:"likes"`
+					Comments   metric `json:"comments"`
+					Shares     metric `json:"shares"`
+					Reach      metric `json:"reach"`
+					Engagement metric `json:"engagement"`
+				} `json:"buckets"`
+			} `json:"posts"`
+		} `json:"aggregations"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	analytics := make(map[string]postAnalytics, len(result.Aggregations.Posts.Buckets))
+	for _, b := range result.Aggregations.Posts.Buckets {
+		analytics[b.Key] = postAnalytics{
+			Likes:      int(b.Likes.Value),
+			Comments:   int(b.Comments.Value),
+			Shares:     int(b.Shares.Value),
+			Reach:      int(b.Reach.Value),
+			Engagement: b.Engagement.Value,
+		}
+	}
+	return analytics, nil
+}
+
+// rollupPageSize is the number of post and group pairs aggregated per request
+const rollupPageSize = 1000
+
+// GetAnalyticsRollup sums the analytics of a user's posts grouped by tag or by
+// campaign. The posts of each group are paged through with a composite
+// aggregation, so there is no cap on how many posts a rollup covers.
+func (es *ElasticsearchDB) GetAnalyticsRollup(userID, groupBy string, filter models.PostFilter) ([]models.AnalyticsRollup, error) {
+	field := "tags"
+	if groupBy == "campaign" {
+		field = "campaign_id"
+	}
+
+	filters := append(postFilterClauses(filter),
+		map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+		map[string]interface{}{"exists": map[string]interface{}{"field": field}},
+	)
+	composite := map[string]interface{}{
+		"size": rollupPageSize,
+		"sources": []interface{}{
+			map[string]interface{}{"group": map[string]interface{}{"terms": map[string]interface{}{"field": field}}},
+			map[string]interface{}{"post": map[string]interface{}{"terms": map[string]interface{}{"field": "id"}}},
+		},
+	}
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter":   filters,
+				"must_not": notDeleted,
+			},
+		},
+		"aggs": map[string]interface{}{
+			"pairs": map[string]interface{}{"composite": composite},
+		},
+	}
+
+	rollups := make(map[string]*models.AnalyticsRollup)
+	var keys []string
+	for {
+		pairs, after, err := es.rollupPairs(query)
+		if err != nil {
+			return nil, err
+		}
+
+		postIDs := make([]string, 0, len(pairs))
+		seen := make(map[string]bool)
+		for _, pair := range pairs {
+			if !seen[pair.Post] {
+				seen[pair.Post] = true
+				postIDs = append(postIDs, pair.Post)
+			}
+		}
+		analytics, err := es.getPostAnalytics(postIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pair := range pairs {
+			r, ok := rollups[pair.Group]
+			if !ok {
+				r = &models.AnalyticsRollup{Key: pair.Group}
+				rollups[pair.Group] = r
+				keys = append(keys, pair.Group)
+			}
+			a := analytics[pair.Post]
+			// Running average of engagement over the posts of the group
+			r.Engagement = (r.Engagement*float64(r.Posts) + a.Engagement) / float64(r.Posts+1)
+			r.Posts++
+			r.Likes += a.Likes
+			r.Comments += a.Comments
+			r.Shares += a.Shares
+			r.Reach += a.Reach
+		}
+
+		if after == nil || len(pairs) < rollupPageSize {
+			break
+		}
+		composite["after"] = after
+	}
+
+	result := make([]models.AnalyticsRollup, len(keys))
+	for i, key := range keys {
+		result[i] = *rollups[key]
+	}
+	return result, nil
+}
+
+// rollupPair is a post and one of the groups it belongs to
+type rollupPair struct {
+	Group string `json:"group"`
+	Post  string `json:"post"`
+}
+
+// rollupPairs runs one page of the rollup aggregation and returns its pairs
+// and the key to continue after
+func (es *ElasticsearchDB) rollupPairs(query map[string]interface{}) ([]rollupPair, map[string]interface{}, error) {
+	res, err := es.client.Search(
+		es.client.Search.WithIndex("posts"),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, nil, fmt.Errorf("error aggregating posts: %s", res.String())
+	}
+
+	var result struct {
+		Aggregations struct {
+			Pairs struct {
+				AfterKey map[string]interface{} `json:"after_key"`
+				Buckets  []struct {
+					Key rollupPair `json:"key"`
+				} `json:"buckets"`
+			} `json:"pairs"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, nil, err
+	}
+
+	pairs := make([]rollupPair, len(result.Aggregations.Pairs.Buckets))
+	for i, b := range result.Aggregations.Pairs.Buckets {
+		pairs[i] = b.Key
+	}
+	return pairs, result.Aggregations.Pairs.AfterKey, nil
+}
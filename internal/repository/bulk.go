@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// bulkAction is one action of a bulk request with its document, if any
+type bulkAction struct {
+	Action string // create, index, update or delete
+	Index  string
+	ID     string
+	Doc    interface{}
+	// Optimistic concurrency control, applied when PrimaryTerm is set
+	SeqNo       int
+	PrimaryTerm int
+}
+
+// bulkItemResult is the outcome of one bulk action
+type bulkItemResult struct {
+	SeqNo       int
+	PrimaryTerm int
+	Err         error
+}
+
+// bulk runs the actions in a single bulk request and returns their results in
+// order. The returned error is only set when the request as a whole failed.
+func (es *ElasticsearchDB) bulk(actions []bulkAction) ([]bulkItemResult, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+
+	var body bytes.Buffer
+	for _, action := range actions {
+		meta := map[string]interface{}{"_index": action.Index, "_id": action.ID}
+		if action.PrimaryTerm > 0 {
+			meta["if_seq_no"] = action.SeqNo
+			meta["if_primary_term"] = action.PrimaryTerm
+		}
+		if err := json.NewEncoder(&body).Encode(map[string]interface{}{action.Action: meta}); err != nil {
+			return nil, err
+		}
+		if action.Doc == nil {
+			continue
+		}
+		if err := json.NewEncoder(&body).Encode(action.Doc); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := es.client.Bulk(
+		&body,
+		es.client.Bulk.WithRefresh("true"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error running bulk request: %s", res.String())
+	}
+
+	var result struct {
+		Items []map[string]struct {
+			Status      int `json:"status"`
+			SeqNo       int `json:"_seq_no"`
+			PrimaryTerm int `json:"_primary_term"`
+			Error       *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Items) != len(actions) {
+		return nil, fmt.Errorf("bulk response has %d items for %d actions", len(result.Items), len(actions))
+	}
+
+	results := make([]bulkItemResult, len(actions))
+	for i, item := range result.Items {
+		for _, outcome := range item {
+			results[i] = bulkItemResult{SeqNo: outcome.SeqNo, PrimaryTerm: outcome.PrimaryTerm}
+			switch {
+			case outcome.Status == 409:
+				results[i].Err = ErrVersionConflict
+			case outcome.Status == 404:
+				results[i].Err = ErrPostNotFound
+			case outcome.Error != nil:
+				results[i].Err = errors.New(outcome.Error.Reason)
+			}
+		}
+	}
+	return results, nil
+}
+
+// BulkCreatePosts creates the posts with a single bulk request and writes their
+// first revisions. The returned slice holds the error of each post, nil for the
+// ones that were created.
+func (es *ElasticsearchDB) BulkCreatePosts(posts []*models.Post) ([]error, error) {
+	if err := es.createIndexIfNotExists("posts"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	actions := make([]bulkAction, len(posts))
+	for i, post := range posts {
+		post.ID = uuid.New().String()
+		post.CreatedAt = now
+		post.UpdatedAt = now
+		post.ScheduledTime = post.ScheduledTime.UTC()
+		post.Version = 1
+		actions[i] = bulkAction{Action: "create", Index: "posts", ID: post.ID, Doc: post}
+	}
+
+	results, err := es.bulk(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(posts))
+	var revisions []bulkAction
+	for i, result := range results {
+		if result.Err != nil {
+			errs[i] = result.Err
+			continue
+		}
+		posts[i].SeqNo = result.SeqNo
+		posts[i].PrimaryTerm = result.PrimaryTerm
+		rev := newPostRevision(nil, posts[i], posts[i].UserID)
+		revisions = append(revisions, bulkAction{Action: "create", Index: "post_revisions", ID: rev.ID, Doc: rev})
+	}
+
+	// Revision failures do not undo the created posts, whose history starts at
+	// the next update instead
+	if _, err := es.bulk(revisions); err != nil {
+		return errs, fmt.Errorf("error creating post revisions: %v", err)
+	}
+	return errs, nil
+}
+
+// GetPostsByIDs fetches the posts with the given IDs, trashed ones included.
+// Missing posts are left out.
+func (es *ElasticsearchDB) GetPostsByIDs(postIDs []string) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": postIDs},
+		},
+		"size": len(postIDs),
+	}
+	return es.searchPosts(query)
+}
+
+// BulkUpdatePosts saves the posts with a single bulk request, each guarded by
+// the sequence number it was read with, and records a revision for every post
+// that was saved. previous holds the state of each post before the change.
+// The returned slice holds the error of each post, nil for the ones that were saved.
+func (es *ElasticsearchDB) BulkUpdatePosts(previous, posts []*models.Post, editorID string) ([]error, error) {
+	now := time.Now()
+	actions := make([]bulkAction, len(posts))
+	for i, post := range posts {
+		post.UpdatedAt = now
+		post.ScheduledTime = post.ScheduledTime.UTC()
+		post.Version++
+		actions[i] = bulkAction{
+			Action:      "index",
+			Index:       "posts",
+			ID:          post.ID,
+			Doc:         post,
+			SeqNo:       post.SeqNo,
+			PrimaryTerm: post.PrimaryTerm,
+		}
+	}
+
+	results, err := es.bulk(actions)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(posts))
+	var revisions []bulkAction
+	for i, result := range results {
+		if result.Err != nil {
+			posts[i].Version--
+			errs[i] = result.Err
+			continue
+		}
+		posts[i].SeqNo = result.SeqNo
+		posts[i].PrimaryTerm = result.PrimaryTerm
+		rev := newPostRevision(previous[i], posts[i], editorID)
+		revisions = append(revisions, bulkAction{Action: "create", Index: "post_revisions", ID: rev.ID, Doc: rev})
+	}
+
+	if _, err := es.bulk(revisions); err != nil {
+		return errs, fmt.Errorf("error creating post revisions: %v", err)
+	}
+	return errs, nil
+}
+
+// UpdatePostsByQuery applies a bulk action to every live post of the user
+// matching the filter of the request in a single update_by_query. The query
+// only matches posts the action applies to, so the script does not have to
+// repeat the checks of BulkOperationRequest.Apply. Changes made this way bump
+// the post version without recording a revision.
+func (es *ElasticsearchDB) UpdatePostsByQuery(userID string, req *models.BulkOperationRequest) (*models.BulkByQueryResult, error) {
+	now := time.Now().UTC()
+	filter := postFilterClauses(req.Filter.PostFilter())
+	mustNot := []interface{}{notDeleted}
+	params := map[string]interface{}{"now": now.Format(time.RFC3339Nano)}
+
+	var script string
+	switch req.Action {
+	case models.BulkActionDelete:
+		script = "ctx._source.deleted_at = params.now;"
+	case models.BulkActionStatus:
+		var from []string
+		for _, status := range []string{
+			models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublishing,
+			models.PostStatusPublished, models.PostStatusFailed, models.PostStatusArchived,
+		} {
+			if models.CanTransition(status, req.Status) {
+				from = append(from, status)
+			}
+		}
+		filter = append(filter, map[string]interface{}{"terms": map[string]interface{}{"status": from}})
+		if req.Status == models.PostStatusScheduled {
+			// Only future schedules, and never a series template
+			filter = append(filter, map[string]interface{}{
+				"range": map[string]interface{}{"scheduled_time": map[string]interface{}{"gt": now}},
+			})
+			mustNot = append(mustNot, map[string]interface{}{"exists": map[string]interface{}{"field": "recurrence"}})
+		}
+		params["status"] = req.Status
+		script = `
+			if (ctx._source.status_history == null) { ctx._source.status_history = []; }
+			ctx._source.status_history.add(['from': ctx._source.status, 'to': params.status, 'at': params.now]);
+			ctx._source.status = params.status;`
+	case models.BulkActionShift:
+		// Drafts can move anywhere, scheduled posts must stay in the future
+		filter = append(filter,
+			map[string]interface{}{"range": map[string]interface{}{"scheduled_time": map[string]interface{}{"gt": "0001-01-01T00:00:00Z"}}},
+			map[string]interface{}{"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"status": models.PostStatusDraft}},
+					map[string]interface{}{"bool": map[string]interface{}{"filter": []interface{}{
+						map[string]interface{}{"term": map[string]interface{}{"status": models.PostStatusScheduled}},
+						map[string]interface{}{"range": map[string]interface{}{"scheduled_time": map[string]interface{}{
+							"gt": now.Add(-time.Duration(req.OffsetSeconds) * time.Second),
+						}}},
+					}}},
+				},
+				"minimum_should_match": 1,
+			}},
+		)
+		params["offset"] = req.OffsetSeconds
+		script = "ctx._source.scheduled_time = Instant.parse(ctx._source.scheduled_time).plusSeconds(params.offset).toString();"
+	case models.BulkActionAddTag:
+		mustNot = append(mustNot, map[string]interface{}{"term": map[string]interface{}{"tags": req.Tag}})
+		params["tag"] = req.Tag
+		script = `
+			if (ctx._source.tags == null) { ctx._source.tags = []; }
+			ctx._source.tags.add(params.tag);`
+	case models.BulkActionRemoveTag:
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"tags": req.Tag}})
+		params["tag"] = req.Tag
+		script = "ctx._source.tags.removeIf(t -> t == params.tag);"
+	default:
+		return nil, fmt.Errorf("action %q cannot be applied by filter", req.Action)
+	}
+
+	if req.Action != models.BulkActionDelete {
+		// Editing a single occurrence detaches it from later changes to the series
+		script += `
+			if (ctx._source.series_id != null && ctx._source.series_id != '') { ctx._source.detached = true; }`
+	}
+	script += `
+		ctx._source.version = (ctx._source.version == null ? 0 : ctx._source.version) + 1;
+		ctx._source.updated_at = params.now;`
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"match": map[string]interface{}{"user_id": userID},
+				},
+				"filter":   filter,
+				"must_not": mustNot,
+			},
+		},
+		"script": map[string]interface{}{
+			"source": script,
+			"lang":   "painless",
+			"params": params,
+		},
+	}
+
+	res, err := es.client.UpdateByQuery(
+		[]string{"posts"},
+		es.client.UpdateByQuery.WithBody(strings.NewReader(toJSON(query))),
+		es.client.UpdateByQuery.WithConflicts("proceed"),
+		es.client.UpdateByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error updating posts by query: %s", res.String())
+	}
+
+	var result struct {
+		Updated          int `json:"updated"`
+		VersionConflicts int `json:"version_conflicts"`
+		Failures         []struct {
+			ID    string `json:"id"`
+			Cause struct {
+				Reason string `json:"reason"`
+			} `json:"cause"`
+		} `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	out := &models.BulkByQueryResult{
+		Updated:          result.Updated,
+		VersionConflicts: result.VersionConflicts,
+		Failed:           []models.BulkItemResult{},
+	}
+	for _, failure := range result.Failures {
+		out.Failed = append(out.Failed, models.BulkItemResult{PostID: failure.ID, Error: failure.Cause.Reason})
+	}
+	return out, nil
+}
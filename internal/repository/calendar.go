@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// calendarBucketSize is the maximum number of posts returned per calendar bucket
+const calendarBucketSize = 100
+
+// GetCalendar buckets the posts of a user by day or week using a date histogram
+func (es *ElasticsearchDB) GetCalendar(userID string, q models.CalendarQuery) ([]models.CalendarBucket, error) {
+	loc, err := time.LoadLocation(q.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{
+				q.Field: map[string]interface{}{
+					"gte": q.From.Format(time.RFC3339),
+					"lt":  q.To.Format(time.RFC3339),
+				},
+			},
+		},
+	}
+	if len(q.AccountIDs) > 0 {
+		filters = append(filters, map[string]interface{}{"terms": map[string]interface{}{"account_id": q.AccountIDs}})
+	}
+	filters = append(filters, postFilterClauses(models.PostFilter{
+		Tags:        q.Tags,
+		CampaignIDs: q.Campaigns,
+		Platforms:   q.Platforms,
+		Statuses:    q.Statuses,
+	})...)
+
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"match": map[string]interface{}{
+						"user_id": userID,
+					},
+				},
+				"filter": filters,
+				"must_not": []interface{}{
+					notDeleted,
+					// Series templates are shown through their materialized occurrences
+					map[string]interface{}{"exists": map[string]interface{}{"field": "recurrence.rrule"}},
+				},
+			},
+		},
+		"aggs": map[string]interface{}{
+			"calendar": map[string]interface{}{
+				"date_histogram": map[string]interface{}{
+					"field":             q.Field,
+					"calendar_interval": q.Interval,
+					"time_zone":         q.Timezone,
+					"min_doc_count":     0,
+					"extended_bounds": map[string]interface{}{
+						"min": q.From.Format(time.RFC3339),
+						"max": q.To.Add(-time.Millisecond).Format(time.RFC3339Nano),
+					},
+				},
+				"aggs": map[string]interface{}{
+					"posts": map[string]interface{}{
+						"top_hits": map[string]interface{}{
+							"size":                calendarBucketSize,
+							"sort":                []interface{}{map[string]interface{}{q.Field: "asc"}},
+							"seq_no_primary_term": true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	res, err := es.client.Search(
+		es.client.Search.WithIndex("posts"),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting calendar: %s", res.String())
+	}
+
+	var result struct {
+		Aggregations struct {
+			Calendar struct {
+				Buckets []struct {
+					Key      int64 `json:"key"`
+					DocCount int   `json:"doc_count"`
+					Posts    struct {
+						Hits struct {
+							Hits []struct {
+								SeqNo       int         `json:"_seq_no"`
+								PrimaryTerm int         `json:"_primary_term"`
+								Source      models.Post `json:"_source"`
+							} `json:"hits"`
+						} `json:"hits"`
+					} `json:"posts"`
+				} `json:"buckets"`
+			} `json:"calendar"`
+		} `json:"aggregations"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]models.CalendarBucket, len(result.Aggregations.Calendar.Buckets))
+	for i, b := range result.Aggregations.Calendar.Buckets {
+		posts := make([]models.Post, len(b.Posts.Hits.Hits))
+		for j, hit := range b.Posts.Hits.Hits {
+			posts[j] = hit.Source.InLocation(loc)
+			posts[j].SeqNo = hit.SeqNo
+			posts[j].PrimaryTerm = hit.PrimaryTerm
+		}
+		buckets[i] = models.CalendarBucket{
+			Date:  time.UnixMilli(b.Key).In(loc),
+			Count: b.DocCount,
+			Posts: posts,
+		}
+	}
+
+	return buckets, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// ErrCampaignNotFound is returned when a campaign does not exist
+var ErrCampaignNotFound = errors.New("campaign not found")
+
+func (es *ElasticsearchDB) CreateCampaign(campaign *models.Campaign) error {
+	campaign.ID = uuid.New().String()
+	campaign.CreatedAt = time.Now()
+	return es.UpdateCampaign(campaign)
+}
+
+// GetCampaign fetches a campaign by ID; deleted campaigns are reported as not found
+func (es *ElasticsearchDB) GetCampaign(campaignID string) (*models.Campaign, error) {
+	var campaign models.Campaign
+	found, err := es.getDocument("campaigns", campaignID, &campaign)
+	if err != nil {
+		return nil, err
+	}
+	if !found || campaign.DeletedAt != nil {
+		return nil, ErrCampaignNotFound
+	}
+	return &campaign, nil
+}
+
+// GetUserCampaigns returns the campaigns of a user, most recent first
+func (es *ElasticsearchDB) GetUserCampaigns(userID string) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	err := es.searchDocuments("campaigns", userDocumentsQueryOrdered(userID, "start_date", "desc"), &campaigns)
+	return campaigns, err
+}
+
+func (es *ElasticsearchDB) UpdateCampaign(campaign *models.Campaign) error {
+	campaign.UpdatedAt = time.Now()
+	return es.saveDocument("campaigns", campaign.ID, campaign)
+}
+
+// DeleteCampaign hides a campaign; posts keep their campaign_id for reporting
+func (es *ElasticsearchDB) DeleteCampaign(campaign *models.Campaign) error {
+	now := time.Now()
+	campaign.DeletedAt = &now
+	return es.UpdateCampaign(campaign)
+}
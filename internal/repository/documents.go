@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errDocumentNotFound is returned when updating a document that does not exist
+var errDocumentNotFound = errors.New("document not found")
+
+// getDocument fetches a document by ID into v and reports whether it exists
+func (es *ElasticsearchDB) getDocument(index, id string, v interface{}) (bool, error) {
+	res, err := es.client.Get(index, id)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return false, nil
+	}
+	if res.IsError() {
+		return false, fmt.Errorf("error getting %s document: %s", index, res.String())
+	}
+
+	var result struct {
+		Source json.RawMessage `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(result.Source, v)
+}
+
+// saveDocument creates or replaces a document
+func (es *ElasticsearchDB) saveDocument(index, id string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	res, err := es.client.Index(
+		index,
+		strings.NewReader(string(data)),
+		es.client.Index.WithDocumentID(id),
+		es.client.Index.WithRefresh("true"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error saving %s document: %s", index, res.String())
+	}
+	return nil
+}
+
+// createDocument creates a document unless one with the same ID exists and
+// reports whether it was created
+func (es *ElasticsearchDB) createDocument(index, id string, v interface{}) (bool, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := es.client.Index(
+		index,
+		strings.NewReader(string(data)),
+		es.client.Index.WithDocumentID(id),
+		es.client.Index.WithOpType("create"),
+		es.client.Index.WithRefresh("true"),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		return false, nil
+	}
+	if res.IsError() {
+		return false, fmt.Errorf("error creating %s document: %s", index, res.String())
+	}
+	return true, nil
+}
+
+// updateDocument sets fields of an existing document, leaving the others unchanged
+func (es *ElasticsearchDB) updateDocument(index, id string, fields map[string]interface{}) error {
+	body := toJSON(map[string]interface{}{"doc": fields})
+	res, err := es.client.Update(
+		index,
+		id,
+		strings.NewReader(body),
+		es.client.Update.WithRefresh("true"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return errDocumentNotFound
+	}
+	if res.IsError() {
+		return fmt.Errorf("error updating %s document: %s", index, res.String())
+	}
+	return nil
+}
+
+// searchDocuments runs a search and decodes the sources of the hits into out,
+// which must be a pointer to a slice
+func (es *ElasticsearchDB) searchDocuments(index string, query map[string]interface{}, out interface{}) error {
+	res, err := es.client.Search(
+		es.client.Search.WithIndex(index),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error searching %s: %s", index, res.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	sources := make([]json.RawMessage, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		sources[i] = hit.Source
+	}
+	data, err := json.Marshal(sources)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// userDocumentsQuery matches the live documents of a user
+func userDocumentsQuery(userID string, sort string) map[string]interface{} {
+	return userDocumentsQueryOrdered(userID, sort, "asc")
+}
+
+// userDocumentsQueryOrdered is userDocumentsQuery with the sort order given
+func userDocumentsQueryOrdered(userID, sort, order string) map[string]interface{} {
+	return map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter":   map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+				"must_not": notDeleted,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{sort: order},
+		},
+		"size": 1000,
+	}
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// GetPostCopies returns the live posts duplicated from the given post, joined
+// with their analytics, together with the analytics of the post itself
+func (es *ElasticsearchDB) GetPostCopies(post *models.Post) (*models.PostExport, []models.PostExport, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"source_post_id": post.ID}},
+					map[string]interface{}{"term": map[string]interface{}{"user_id": post.UserID}},
+				},
+				"must_not": notDeleted,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"created_at": "asc"},
+		},
+		"size": 1000,
+	}
+
+	copies, err := es.searchPosts(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	postIDs := []string{post.ID}
+	for _, p := range copies {
+		postIDs = append(postIDs, p.ID)
+	}
+	analytics, err := es.getPostAnalytics(postIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	source := newPostExport(*post, analytics[post.ID])
+	joined := make([]models.PostExport, len(copies))
+	for i, p := range copies {
+		joined[i] = newPostExport(p, analytics[p.ID])
+	}
+	return &source, joined, nil
+}
+
+// MediaURLInUse reports whether a media file is referenced by any post other
+// than the given one or its parts, trashed posts included. Duplicated posts
+// share their files.
+func (es *ElasticsearchDB) MediaURLInUse(url, exceptPostID string) (bool, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": postMediaQuery("url", url),
+				"must_not": map[string]interface{}{
+					"ids": map[string]interface{}{"values": []string{exceptPostID}},
+				},
+			},
+		},
+		"size": 1,
+	}
+	return es.Exists("posts", query)
+}
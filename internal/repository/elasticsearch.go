@@ -2,16 +2,25 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/google/uuid"
 	"github.com/priince9381/irm_backend/internal/config"
 	"github.com/priince9381/irm_backend/internal/models"
 )
 
+// ErrPostNotFound is returned when a post does not exist
+var ErrPostNotFound = errors.New("post not found")
+
+// ErrVersionConflict is returned when a post was modified since it was read
+var ErrVersionConflict = errors.New("version conflict")
+
 type ElasticsearchDB struct {
 	client *elasticsearch.Client
 }
@@ -36,14 +45,18 @@ func NewElasticsearchDB(cfg *config.Config) (*ElasticsearchDB, error) {
 	return esDB, nil
 }
 
-// Helper function to create index if not exists
+// Helper function to create index if not exists, applying its mapping when one is defined
 func (es *ElasticsearchDB) createIndexIfNotExists(index string) error {
 	res, err := es.client.Indices.Exists([]string{index})
 	if err != nil {
 		return err
 	}
 	if res.StatusCode == 404 {
-		_, err = es.client.Indices.Create(index)
+		var opts []func(*esapi.IndicesCreateRequest)
+		if mapping, ok := indexMappings[index]; ok {
+			opts = append(opts, es.client.Indices.Create.WithBody(strings.NewReader(mapping)))
+		}
+		_, err = es.client.Indices.Create(index, opts...)
 		if err != nil {
 			return err
 		}
@@ -51,11 +64,12 @@ func (es *ElasticsearchDB) createIndexIfNotExists(index string) error {
 	return nil
 }
 
-// CreateIndices creates all required indices
+// CreateIndices creates all required indices and migrates the mappings of
+// existing ones
 func (es *ElasticsearchDB) CreateIndices() error {
-	indices := []string{"users", "posts"}
+	indices := []string{"users", "user_preferences", "social_accounts", "posts", "post_revisions", "queues", "campaigns", "templates", "snippets", "media", "media_uploads", "storage_usage", "analytics"}
 	for _, index := range indices {
-		if err := es.createIndexIfNotExists(index); err != nil {
+		if err := es.ensureIndex(index); err != nil {
 			return err
 		}
 	}
@@ -137,30 +151,103 @@ func (es *ElasticsearchDB) CreatePost(post *models.Post) error {
 	post.ID = uuid.New().String()
 	post.CreatedAt = time.Now()
 	post.UpdatedAt = time.Now()
+	post.ScheduledTime = post.ScheduledTime.UTC()
+	post.Version = 1
 
 	data, err := json.Marshal(post)
 	if err != nil {
 		return err
 	}
 
-	_, err = es.client.Index(
+	res, err := es.client.Index(
 		"posts",
 		strings.NewReader(string(data)),
 		es.client.Index.WithDocumentID(post.ID),
 		es.client.Index.WithRefresh("true"),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error creating post: %s", res.String())
+	}
+
+	var result struct {
+		SeqNo       int `json:"_seq_no"`
+		PrimaryTerm int `json:"_primary_term"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	post.SeqNo = result.SeqNo
+	post.PrimaryTerm = result.PrimaryTerm
+
+	// The post exists either way; without its first revision its history
+	// starts at the next update
+	if err := es.createPostRevision(nil, post, post.UserID); err != nil {
+		log.Printf("Failed to create the first revision of post %s: %v", post.ID, err)
+	}
+	return nil
 }
 
-func (es *ElasticsearchDB) GetUserPosts(userID string) ([]models.Post, error) {
-	query := map[string]interface{}{
-		"query": map[string]interface{}{
+// GetUserPosts returns the posts of a user matching the filter
+func (es *ElasticsearchDB) GetUserPosts(userID string, filter models.PostFilter) ([]models.Post, error) {
+	must := []interface{}{
+		map[string]interface{}{
 			"match": map[string]interface{}{
 				"user_id": userID,
 			},
 		},
 	}
+	if filter.Query != "" {
+		must = append(must, map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  filter.Query,
+				"fields": []string{"title", "content"},
+			},
+		})
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must":     must,
+				"filter":   postFilterClauses(filter),
+				"must_not": notDeleted,
+			},
+		},
+	}
+
+	return es.searchPosts(query)
+}
+
+// postFilterClauses turns the term filters of a PostFilter into bool filter clauses
+func postFilterClauses(filter models.PostFilter) []interface{} {
+	clauses := []interface{}{}
+	for field, values := range map[string][]string{
+		"tags":        filter.Tags,
+		"campaign_id": filter.CampaignIDs,
+		"platforms":   filter.Platforms,
+		"status":      filter.Statuses,
+	} {
+		if len(values) > 0 {
+			clauses = append(clauses, map[string]interface{}{"terms": map[string]interface{}{field: values}})
+		}
+	}
+	return clauses
+}
 
+// notDeleted excludes soft-deleted posts when used in a bool must_not clause
+var notDeleted = map[string]interface{}{
+	"exists": map[string]interface{}{
+		"field": "deleted_at",
+	},
+}
+
+// searchPosts runs a search against the posts index and decodes the hits
+func (es *ElasticsearchDB) searchPosts(query map[string]interface{}) ([]models.Post, error) {
 	data, err := json.Marshal(query)
 	if err != nil {
 		return nil, err
@@ -169,16 +256,23 @@ func (es *ElasticsearchDB) GetUserPosts(userID string) ([]models.Post, error) {
 	res, err := es.client.Search(
 		es.client.Search.WithIndex("posts"),
 		es.client.Search.WithBody(strings.NewReader(string(data))),
+		es.client.Search.WithSeqNoPrimaryTerm(true),
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Body.Close()
 
+	if res.IsError() {
+		return nil, fmt.Errorf("error searching posts: %s", res.String())
+	}
+
 	var result struct {
 		Hits struct {
 			Hits []struct {
-				Source models.Post `json:"_source"`
+				SeqNo       int         `json:"_seq_no"`
+				PrimaryTerm int         `json:"_primary_term"`
+				Source      models.Post `json:"_source"`
 			} `json:"hits"`
 		} `json:"hits"`
 	}
@@ -190,30 +284,104 @@ func (es *ElasticsearchDB) GetUserPosts(userID string) ([]models.Post, error) {
 	posts := make([]models.Post, len(result.Hits.Hits))
 	for i, hit := range result.Hits.Hits {
 		posts[i] = hit.Source
+		posts[i].SeqNo = hit.SeqNo
+		posts[i].PrimaryTerm = hit.PrimaryTerm
 	}
 
 	return posts, nil
 }
 
-func (es *ElasticsearchDB) UpdatePost(post *models.Post) error {
+// GetPost fetches a single post by its ID
+func (es *ElasticsearchDB) GetPost(postID string) (*models.Post, error) {
+	res, err := es.client.Get("posts", postID)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, ErrPostNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting post: %s", res.String())
+	}
+
+	var result struct {
+		SeqNo       int         `json:"_seq_no"`
+		PrimaryTerm int         `json:"_primary_term"`
+		Source      models.Post `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	post := result.Source
+	post.SeqNo = result.SeqNo
+	post.PrimaryTerm = result.PrimaryTerm
+	return &post, nil
+}
+
+// UpdatePost re-indexes a post and records the change as a new revision made by editorID.
+// When the post carries a sequence number and primary term the write only succeeds if
+// the stored document has not changed since, otherwise ErrVersionConflict is returned.
+func (es *ElasticsearchDB) UpdatePost(post *models.Post, editorID string) error {
+	previous, err := es.GetPost(post.ID)
+	if err != nil && !errors.Is(err, ErrPostNotFound) {
+		return err
+	}
+
 	post.UpdatedAt = time.Now()
+	post.ScheduledTime = post.ScheduledTime.UTC()
+	post.NextCommentAt = post.NextCommentDue()
+	post.Version++
 	data, err := json.Marshal(post)
 	if err != nil {
 		return err
 	}
 
-	_, err = es.client.Index(
-		"posts",
-		strings.NewReader(string(data)),
+	opts := []func(*esapi.IndexRequest){
 		es.client.Index.WithDocumentID(post.ID),
 		es.client.Index.WithRefresh("true"),
-	)
-	return err
+	}
+	if post.PrimaryTerm > 0 {
+		opts = append(opts,
+			es.client.Index.WithIfSeqNo(post.SeqNo),
+			es.client.Index.WithIfPrimaryTerm(post.PrimaryTerm),
+		)
+	}
+
+	res, err := es.client.Index("posts", strings.NewReader(string(data)), opts...)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		return ErrVersionConflict
+	}
+	if res.IsError() {
+		return fmt.Errorf("error updating post: %s", res.String())
+	}
+
+	var result struct {
+		SeqNo       int `json:"_seq_no"`
+		PrimaryTerm int `json:"_primary_term"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	post.SeqNo = result.SeqNo
+	post.PrimaryTerm = result.PrimaryTerm
+
+	return es.createPostRevision(previous, post, editorID)
 }
 
-func (es *ElasticsearchDB) DeletePost(postID string) error {
-	_, err := es.client.Delete("posts", postID)
-	return err
+// DeletePost moves a post to the trash. It stays hidden from queries until it is
+// restored or purged once the retention period has passed.
+func (es *ElasticsearchDB) DeletePost(post *models.Post, editorID string) error {
+	now := time.Now()
+	post.DeletedAt = &now
+	return es.UpdatePost(post, editorID)
 }
 
 // Exists checks if a document exists in the given index based on the query
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+const (
+	// exportPageSize is the number of posts fetched per page of an export
+	exportPageSize = 500
+	// pitKeepAlive is how long a point in time is kept open between pages,
+	// which covers a slow client reading the previous page
+	pitKeepAlive = "5m"
+)
+
+// StreamPostExports walks the posts of a user matching the query in scheduled
+// order and passes them to fn a page at a time, joined with their analytics.
+// A point in time keeps the pages consistent while fn writes them out.
+func (es *ElasticsearchDB) StreamPostExports(userID string, q models.ExportQuery, fn func([]models.PostExport) error) error {
+	filter := postFilterClauses(q.Filter)
+	if !q.From.IsZero() || !q.To.IsZero() {
+		scheduled := map[string]interface{}{}
+		if !q.From.IsZero() {
+			scheduled["gte"] = q.From
+		}
+		if !q.To.IsZero() {
+			scheduled["lt"] = q.To
+		}
+		filter = append(filter, map[string]interface{}{
+			"range": map[string]interface{}{"scheduled_time": scheduled},
+		})
+	}
+
+	pitID, err := es.openPointInTime("posts")
+	if err != nil {
+		return err
+	}
+	defer func() { es.closePointInTime(pitID) }()
+
+	var searchAfter []interface{}
+	for {
+		query := map[string]interface{}{
+			"query": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must": map[string]interface{}{
+						"match": map[string]interface{}{"user_id": userID},
+					},
+					"filter":   filter,
+					"must_not": notDeleted,
+				},
+			},
+			"pit": map[string]interface{}{"id": pitID, "keep_alive": pitKeepAlive},
+			"sort": []interface{}{
+				map[string]interface{}{"scheduled_time": "asc"},
+				map[string]interface{}{"_shard_doc": "asc"},
+			},
+			"size": exportPageSize,
+		}
+		if searchAfter != nil {
+			query["search_after"] = searchAfter
+		}
+
+		posts, nextPitID, nextSearchAfter, err := es.searchPostsPage(query)
+		if err != nil {
+			return err
+		}
+		if nextPitID != "" {
+			pitID = nextPitID
+		}
+		searchAfter = nextSearchAfter
+		if len(posts) == 0 {
+			return nil
+		}
+
+		postIDs := make([]string, len(posts))
+		for i, post := range posts {
+			postIDs[i] = post.ID
+		}
+		analytics, err := es.getPostAnalytics(postIDs)
+		if err != nil {
+			return err
+		}
+
+		exports := make([]models.PostExport, len(posts))
+		for i, post := range posts {
+			exports[i] = newPostExport(post, analytics[post.ID])
+		}
+		if err := fn(exports); err != nil {
+			return err
+		}
+		if len(posts) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// searchPostsPage runs a point in time search and returns the hits with the
+// refreshed point in time ID and the sort values of the last hit
+func (es *ElasticsearchDB) searchPostsPage(query map[string]interface{}) ([]models.Post, string, []interface{}, error) {
+	// The index comes from the point in time and must not be part of the path
+	res, err := es.client.Search(
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, "", nil, fmt.Errorf("error searching posts: %s", res.String())
+	}
+
+	var result struct {
+		PitID string `json:"pit_id"`
+		Hits  struct {
+			Hits []struct {
+				Source models.Post   `json:"_source"`
+				Sort   []interface{} `json:"sort"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, "", nil, err
+	}
+
+	posts := make([]models.Post, len(result.Hits.Hits))
+	var searchAfter []interface{}
+	for i, hit := range result.Hits.Hits {
+		posts[i] = hit.Source
+		searchAfter = hit.Sort
+	}
+	return posts, result.PitID, searchAfter, nil
+}
+
+func (es *ElasticsearchDB) openPointInTime(index string) (string, error) {
+	res, err := es.client.OpenPointInTime([]string{index}, pitKeepAlive)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", fmt.Errorf("error opening point in time: %s", res.String())
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
+
+func (es *ElasticsearchDB) closePointInTime(pitID string) error {
+	body := toJSON(map[string]interface{}{"id": pitID})
+	res, err := es.client.ClosePointInTime(
+		es.client.ClosePointInTime.WithBody(strings.NewReader(body)),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error closing point in time: %s", res.String())
+	}
+	return nil
+}
+
+func newPostExport(post models.Post, a postAnalytics) models.PostExport {
+	return models.PostExport{
+		Post:       post,
+		Likes:      a.Likes,
+		Comments:   a.Comments,
+		Shares:     a.Shares,
+		Reach:      a.Reach,
+		Engagement: a.Engagement,
+	}
+}
@@ -16,6 +16,15 @@ var indexMappings = map[string]string{
 			}
 		}
 	}`,
+	"user_preferences": `{
+		"mappings": {
+			"properties": {
+				"user_id": { "type": "keyword" },
+				"timezone": { "type": "keyword" },
+				"updated_at": { "type": "date" }
+			}
+		}
+	}`,
 	"social_accounts": `{
 		"mappings": {
 			"properties": {
@@ -39,10 +48,182 @@ var indexMappings = map[string]string{
 				"user_id": { "type": "keyword" },
 				"content": { "type": "text" },
 				"media_urls": { "type": "keyword" },
+				"media_files": {
+					"properties": {
+						"id": { "type": "keyword" },
+						"key": { "type": "keyword" },
+						"url": { "type": "keyword" },
+						"type": { "type": "keyword" },
+						"file_name": { "type": "keyword" },
+						"content_type": { "type": "keyword" },
+						"size": { "type": "long" },
+						"width": { "type": "integer" },
+						"height": { "type": "integer" },
+						"renditions": { "type": "object", "enabled": false },
+						"video": {
+							"properties": {
+								"duration_seconds": { "type": "float" },
+								"video_codec": { "type": "keyword" },
+								"audio_codec": { "type": "keyword" },
+								"frame_rate": { "type": "float" },
+								"bitrate": { "type": "long" }
+							}
+						},
+						"alt_text": { "type": "text" }
+					}
+				},
 				"platforms": { "type": "keyword" },
+				"account_id": { "type": "keyword" },
+				"title": { "type": "text" },
+				"tags": { "type": "keyword" },
+				"campaign_id": { "type": "keyword" },
 				"status": { "type": "keyword" },
+				"status_history": {
+					"properties": {
+						"from": { "type": "keyword" },
+						"to": { "type": "keyword" },
+						"at": { "type": "date" }
+					}
+				},
 				"scheduled_for": { "type": "date" },
+				"scheduled_time": { "type": "date" },
+				"timezone": { "type": "keyword" },
 				"published_at": { "type": "date" },
+				"version": { "type": "integer" },
+				"parts": {
+					"properties": {
+						"content": { "type": "text" },
+						"media_files": {
+							"properties": {
+								"id": { "type": "keyword" },
+								"key": { "type": "keyword" },
+								"url": { "type": "keyword" },
+								"type": { "type": "keyword" },
+								"renditions": { "type": "object", "enabled": false }
+							}
+						},
+						"media_ids": { "type": "keyword" },
+						"platform_ids": { "type": "object", "enabled": false }
+					}
+				},
+				"comments": {
+					"properties": {
+						"id": { "type": "keyword" },
+						"content": { "type": "text" },
+						"offset_seconds": { "type": "integer" },
+						"platforms": { "type": "keyword" },
+						"status": { "type": "keyword" },
+						"platform_ids": { "type": "object", "enabled": false },
+						"error": { "type": "text" },
+						"attempts": { "type": "integer" },
+						"posted_at": { "type": "date" }
+					}
+				},
+				"next_comment_at": { "type": "date" },
+				"platform_ids": { "type": "object", "enabled": false },
+				"publish_error": { "type": "text" },
+				"recurrence": {
+					"properties": {
+						"rrule": { "type": "keyword" },
+						"timezone": { "type": "keyword" },
+						"start": { "type": "date" },
+						"exceptions": { "type": "date" },
+						"materialized_until": { "type": "date" },
+						"ended_at": { "type": "date" }
+					}
+				},
+				"series_id": { "type": "keyword" },
+				"occurrence_time": { "type": "date" },
+				"detached": { "type": "boolean" },
+				"source_post_id": { "type": "keyword" },
+				"created_at": { "type": "date" },
+				"updated_at": { "type": "date" },
+				"deleted_at": { "type": "date" }
+			}
+		}
+	}`,
+	"post_revisions": `{
+		"mappings": {
+			"properties": {
+				"id": { "type": "keyword" },
+				"post_id": { "type": "keyword" },
+				"version": { "type": "integer" },
+				"editor_id": { "type": "keyword" },
+				"changes": { "type": "object", "enabled": false },
+				"snapshot": { "type": "object", "enabled": false },
+				"created_at": { "type": "date" }
+			}
+		}
+	}`,
+	"queues": `{
+		"mappings": {
+			"properties": {
+				"account_id": { "type": "keyword" },
+				"user_id": { "type": "keyword" },
+				"timezone": { "type": "keyword" },
+				"slots": {
+					"properties": {
+						"weekday": { "type": "integer" },
+						"time": { "type": "keyword" }
+					}
+				},
+				"post_ids": { "type": "keyword" },
+				"paused": { "type": "boolean" },
+				"created_at": { "type": "date" },
+				"updated_at": { "type": "date" },
+				"deleted_at": { "type": "date" }
+			}
+		}
+	}`,
+	"campaigns": `{
+		"mappings": {
+			"properties": {
+				"id": { "type": "keyword" },
+				"user_id": { "type": "keyword" },
+				"name": { "type": "text" },
+				"goal": { "type": "text" },
+				"start_date": { "type": "date" },
+				"end_date": { "type": "date" },
+				"utm": {
+					"properties": {
+						"source": { "type": "keyword" },
+						"medium": { "type": "keyword" },
+						"campaign": { "type": "keyword" },
+						"term": { "type": "keyword" },
+						"content": { "type": "keyword" }
+					}
+				},
+				"created_at": { "type": "date" },
+				"updated_at": { "type": "date" },
+				"deleted_at": { "type": "date" }
+			}
+		}
+	}`,
+	"templates": `{
+		"mappings": {
+			"properties": {
+				"id": { "type": "keyword" },
+				"user_id": { "type": "keyword" },
+				"name": { "type": "text", "fields": { "keyword": { "type": "keyword" } } },
+				"title": { "type": "text" },
+				"content": { "type": "text" },
+				"platforms": { "type": "keyword" },
+				"links": { "type": "keyword" },
+				"tags": { "type": "keyword" },
+				"variables": { "type": "keyword" },
+				"created_at": { "type": "date" },
+				"updated_at": { "type": "date" },
+				"deleted_at": { "type": "date" }
+			}
+		}
+	}`,
+	"snippets": `{
+		"mappings": {
+			"properties": {
+				"id": { "type": "keyword" },
+				"user_id": { "type": "keyword" },
+				"name": { "type": "keyword" },
+				"content": { "type": "text" },
 				"created_at": { "type": "date" },
 				"updated_at": { "type": "date" },
 				"deleted_at": { "type": "date" }
@@ -54,14 +235,70 @@ var indexMappings = map[string]string{
 			"properties": {
 				"id": { "type": "keyword" },
 				"user_id": { "type": "keyword" },
+				"key": { "type": "keyword" },
 				"url": { "type": "keyword" },
 				"type": { "type": "keyword" },
+				"file_name": { "type": "search_as_you_type" },
+				"content_type": { "type": "keyword" },
+				"size": { "type": "long" },
+				"sha256": { "type": "keyword" },
+				"width": { "type": "integer" },
+				"height": { "type": "integer" },
+				"renditions": { "type": "object", "enabled": false },
+				"video": {
+					"properties": {
+						"duration_seconds": { "type": "float" },
+						"video_codec": { "type": "keyword" },
+						"audio_codec": { "type": "keyword" },
+						"frame_rate": { "type": "float" },
+						"bitrate": { "type": "long" }
+					}
+				},
+				"processing": { "type": "keyword" },
+				"processing_error": { "type": "text" },
+				"tags": { "type": "keyword" },
+				"alt_text": { "type": "text" },
+				"caption": { "type": "text" },
 				"created_at": { "type": "date" },
 				"updated_at": { "type": "date" },
 				"deleted_at": { "type": "date" }
 			}
 		}
 	}`,
+	"media_uploads": `{
+		"mappings": {
+			"properties": {
+				"id": { "type": "keyword" },
+				"user_id": { "type": "keyword" },
+				"key": { "type": "keyword" },
+				"file_name": { "type": "keyword" },
+				"content_type": { "type": "keyword" },
+				"size": { "type": "long" },
+				"sha256": { "type": "keyword" },
+				"resumable": { "type": "boolean" },
+				"expires_at": { "type": "date" },
+				"completed_at": { "type": "date" },
+				"created_at": { "type": "date" }
+			}
+		}
+	}`,
+	"storage_usage": `{
+		"mappings": {
+			"properties": {
+				"user_id": { "type": "keyword" },
+				"plan": { "type": "keyword" },
+				"bytes": { "type": "long" },
+				"files": { "type": "integer" },
+				"quota": {
+					"properties": {
+						"max_bytes": { "type": "long" },
+						"max_files": { "type": "integer" }
+					}
+				},
+				"updated_at": { "type": "date" }
+			}
+		}
+	}`,
 	"analytics": `{
 		"mappings": {
 			"properties": {
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// ErrMediaNotFound is returned when a media asset does not exist
+var ErrMediaNotFound = errors.New("media not found")
+
+// ErrMediaUploadNotFound is returned when a direct upload does not exist
+var ErrMediaUploadNotFound = errors.New("media upload not found")
+
+// CreateMedia adds an asset to the library and to the storage usage of its
+// user, failing with models.ErrStorageQuotaExceeded when it does not fit.
+// Images are queued for processing.
+func (es *ElasticsearchDB) CreateMedia(asset *models.MediaAsset) error {
+	asset.ID = uuid.New().String()
+	asset.CreatedAt = time.Now()
+	if asset.Type == "image" {
+		asset.Processing = models.MediaProcessingPending
+	}
+
+	if err := es.ReserveStorage(asset.UserID, asset.Size); err != nil {
+		return err
+	}
+	if err := es.UpdateMedia(asset); err != nil {
+		es.AddStorageUsage(asset.UserID, -asset.Size, -1)
+		return err
+	}
+	return nil
+}
+
+// GetPendingMedia returns the oldest assets waiting to be processed
+func (es *ElasticsearchDB) GetPendingMedia(size int) ([]models.MediaAsset, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"processing": models.MediaProcessingPending},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"created_at": "asc"},
+		},
+		"size": size,
+	}
+
+	var assets []models.MediaAsset
+	err := es.searchDocuments("media", query, &assets)
+	return assets, err
+}
+
+// GetUserMediaByHash returns the oldest library asset of a user whose file, as
+// uploaded, has the given SHA-256
+func (es *ElasticsearchDB) GetUserMediaByHash(userID, sha256 string) (*models.MediaAsset, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+					map[string]interface{}{"term": map[string]interface{}{"sha256": sha256}},
+				},
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"created_at": "asc"},
+		},
+		"size": 1,
+	}
+
+	var assets []models.MediaAsset
+	if err := es.searchDocuments("media", query, &assets); err != nil {
+		return nil, err
+	}
+	if len(assets) == 0 {
+		return nil, ErrMediaNotFound
+	}
+	return &assets[0], nil
+}
+
+func (es *ElasticsearchDB) GetMedia(mediaID string) (*models.MediaAsset, error) {
+	var asset models.MediaAsset
+	found, err := es.getDocument("media", mediaID, &asset)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrMediaNotFound
+	}
+	return &asset, nil
+}
+
+// GetMediaByIDs fetches the assets with the given IDs keyed by ID. Missing
+// assets are left out.
+func (es *ElasticsearchDB) GetMediaByIDs(mediaIDs []string) (map[string]models.MediaAsset, error) {
+	assets := make(map[string]models.MediaAsset, len(mediaIDs))
+	if len(mediaIDs) == 0 {
+		return assets, nil
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": mediaIDs},
+		},
+		"size": len(mediaIDs),
+	}
+	var found []models.MediaAsset
+	if err := es.searchDocuments("media", query, &found); err != nil {
+		return nil, err
+	}
+	for _, asset := range found {
+		assets[asset.ID] = asset
+	}
+	return assets, nil
+}
+
+// SearchUserMedia returns the media library of a user, newest first
+func (es *ElasticsearchDB) SearchUserMedia(userID string, q models.MediaQuery) ([]models.MediaAsset, error) {
+	filter := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+	}
+	if q.Type != "" {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"type": q.Type}})
+	}
+	if len(q.Tags) > 0 {
+		filter = append(filter, map[string]interface{}{"terms": map[string]interface{}{"tags": q.Tags}})
+	}
+
+	boolQuery := map[string]interface{}{"filter": filter}
+	if q.Query != "" {
+		boolQuery["must"] = map[string]interface{}{
+			"multi_match": map[string]interface{}{
+				"query":  q.Query,
+				"fields": []string{"file_name", "alt_text", "caption"},
+				"type":   "bool_prefix",
+			},
+		}
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{"bool": boolQuery},
+		"sort": []interface{}{
+			map[string]interface{}{"created_at": "desc"},
+		},
+		"size": 1000,
+	}
+
+	var assets []models.MediaAsset
+	err := es.searchDocuments("media", query, &assets)
+	return assets, err
+}
+
+func (es *ElasticsearchDB) UpdateMedia(asset *models.MediaAsset) error {
+	asset.UpdatedAt = time.Now()
+	return es.saveDocument("media", asset.ID, asset)
+}
+
+// UpdateMediaProcessing saves the outcome of processing an asset without
+// overwriting descriptions edited in the meantime. ErrMediaNotFound is returned
+// when the asset was deleted.
+func (es *ElasticsearchDB) UpdateMediaProcessing(asset *models.MediaAsset) error {
+	asset.UpdatedAt = time.Now()
+	err := es.updateDocument("media", asset.ID, map[string]interface{}{
+		"width":            asset.Width,
+		"height":           asset.Height,
+		"renditions":       asset.Renditions,
+		"processing":       asset.Processing,
+		"processing_error": asset.ProcessingError,
+		"updated_at":       asset.UpdatedAt,
+	})
+	if errors.Is(err, errDocumentNotFound) {
+		return ErrMediaNotFound
+	}
+	return err
+}
+
+// DeleteMedia removes an asset from the library and from the storage usage of
+// its user. The stored file is not touched.
+func (es *ElasticsearchDB) DeleteMedia(asset *models.MediaAsset) error {
+	// Usage first counted after the delete would already leave the asset out
+	if _, err := es.GetStorageUsage(asset.UserID); err != nil {
+		return err
+	}
+
+	res, err := es.client.Delete("media", asset.ID, es.client.Delete.WithRefresh("true"))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil
+	}
+	if res.IsError() {
+		return fmt.Errorf("error deleting media: %s", res.String())
+	}
+	return es.AddStorageUsage(asset.UserID, -asset.Size, -1)
+}
+
+// MediaInUse reports whether any post, trashed ones included, uses the asset
+// in its own media or in the media of its thread or carousel parts
+func (es *ElasticsearchDB) MediaInUse(mediaID string) (bool, error) {
+	query := map[string]interface{}{
+		"query": postMediaQuery("id", mediaID),
+		"size":  1,
+	}
+	return es.Exists("posts", query)
+}
+
+// postMediaQuery matches posts with a media file, in the post itself or in
+// one of its parts, whose field has the given value
+func postMediaQuery(field, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				map[string]interface{}{"term": map[string]interface{}{"media_files." + field: value}},
+				map[string]interface{}{"term": map[string]interface{}{"parts.media_files." + field: value}},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+func (es *ElasticsearchDB) CreateMediaUpload(upload *models.MediaUpload) error {
+	upload.ID = uuid.New().String()
+	upload.CreatedAt = time.Now()
+	return es.UpdateMediaUpload(upload)
+}
+
+func (es *ElasticsearchDB) UpdateMediaUpload(upload *models.MediaUpload) error {
+	return es.saveDocument("media_uploads", upload.ID, upload)
+}
+
+// GetMediaUploadsExpiredBefore returns uploads that expired before the given time
+func (es *ElasticsearchDB) GetMediaUploadsExpiredBefore(cutoff time.Time, size int) ([]models.MediaUpload, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"expires_at": map[string]interface{}{
+					"lt": cutoff.Format(time.RFC3339),
+				},
+			},
+		},
+		"size": size,
+	}
+
+	var uploads []models.MediaUpload
+	err := es.searchDocuments("media_uploads", query, &uploads)
+	return uploads, err
+}
+
+// MediaKeyInUse reports whether a stored file is used by a library asset other
+// than the given one or by a post or its parts. Files are stored under the hash of their
+// content, so several assets and posts can share one.
+func (es *ElasticsearchDB) MediaKeyInUse(key, exceptMediaID string) (bool, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"term": map[string]interface{}{"key": key},
+				},
+				"must_not": map[string]interface{}{
+					"ids": map[string]interface{}{"values": []string{exceptMediaID}},
+				},
+			},
+		},
+		"size": 1,
+	}
+	inUse, err := es.Exists("media", query)
+	if err != nil || inUse {
+		return inUse, err
+	}
+
+	query = map[string]interface{}{
+		"query": postMediaQuery("key", key),
+		"size":  1,
+	}
+	return es.Exists("posts", query)
+}
+
+// GetMediaUploadByKey returns the direct upload that receives the file at key
+func (es *ElasticsearchDB) GetMediaUploadByKey(key string) (*models.MediaUpload, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"key": key},
+		},
+		"size": 1,
+	}
+
+	var uploads []models.MediaUpload
+	if err := es.searchDocuments("media_uploads", query, &uploads); err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, ErrMediaUploadNotFound
+	}
+	return &uploads[0], nil
+}
+
+func (es *ElasticsearchDB) GetMediaUpload(uploadID string) (*models.MediaUpload, error) {
+	var upload models.MediaUpload
+	found, err := es.getDocument("media_uploads", uploadID, &upload)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrMediaUploadNotFound
+	}
+	return &upload, nil
+}
+
+func (es *ElasticsearchDB) DeleteMediaUpload(uploadID string) error {
+	res, err := es.client.Delete("media_uploads", uploadID, es.client.Delete.WithRefresh("true"))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("error deleting media upload: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ensureIndex creates an index with its mapping, or brings the mapping of an
+// existing index up to date. New fields are added in place. An index whose
+// fields were mapped differently before, such as IDs that dynamic mapping
+// indexed as text, cannot be changed in place: it is reindexed into a new
+// index that takes over its name as an alias. This runs at startup, before
+// requests are served, so no writes are lost during the reindex.
+func (es *ElasticsearchDB) ensureIndex(index string) error {
+	mapping, ok := indexMappings[index]
+	if !ok {
+		return es.createIndexIfNotExists(index)
+	}
+
+	res, err := es.client.Indices.Exists([]string{index})
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode == 404 {
+		return es.createIndexIfNotExists(index)
+	}
+
+	var body struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(mapping), &body); err != nil {
+		return fmt.Errorf("invalid mapping of %s: %v", index, err)
+	}
+	res, err = es.client.Indices.PutMapping([]string{index}, strings.NewReader(string(body.Mappings)))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if !res.IsError() {
+		return nil
+	}
+	if res.StatusCode != 400 {
+		return fmt.Errorf("error updating mapping of %s: %s", index, res.String())
+	}
+	log.Printf("Mapping of %s conflicts with existing fields, reindexing: %s", index, res.String())
+	return es.reindex(index, mapping)
+}
+
+// reindex copies an index into a new one with the given mapping and points
+// the name of the old index at the new one
+func (es *ElasticsearchDB) reindex(index, mapping string) error {
+	// The indices currently behind the name, which is an alias after an earlier migration
+	res, err := es.client.Indices.Get([]string{index})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error getting index %s: %s", index, res.String())
+	}
+	var current map[string]json.RawMessage
+	if err := json.NewDecoder(res.Body).Decode(&current); err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("%s_%d", index, time.Now().Unix())
+	res, err = es.client.Indices.Create(target, es.client.Indices.Create.WithBody(strings.NewReader(mapping)))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error creating index %s: %s", target, res.String())
+	}
+
+	if err := es.copyIndex(index, target); err != nil {
+		if res, deleteErr := es.client.Indices.Delete([]string{target}); deleteErr == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+
+	actions := []interface{}{
+		map[string]interface{}{"add": map[string]interface{}{"index": target, "alias": index}},
+	}
+	for name := range current {
+		actions = append(actions, map[string]interface{}{"remove_index": map[string]interface{}{"index": name}})
+	}
+	res, err = es.client.Indices.UpdateAliases(strings.NewReader(toJSON(map[string]interface{}{"actions": actions})))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error switching %s to %s: %s", index, target, res.String())
+	}
+	log.Printf("Reindexed %s into %s", index, target)
+	return nil
+}
+
+// copyIndex copies every document of one index into another
+func (es *ElasticsearchDB) copyIndex(source, dest string) error {
+	body := toJSON(map[string]interface{}{
+		"source": map[string]interface{}{"index": source},
+		"dest":   map[string]interface{}{"index": dest},
+	})
+	res, err := es.client.Reindex(
+		strings.NewReader(body),
+		es.client.Reindex.WithWaitForCompletion(true),
+		es.client.Reindex.WithRefresh(true),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return fmt.Errorf("error reindexing %s: %s", source, res.String())
+	}
+
+	var result struct {
+		Failures []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	if len(result.Failures) > 0 {
+		return fmt.Errorf("error reindexing %s: %d documents failed, first: %s", source, len(result.Failures), result.Failures[0])
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// ErrPreferencesNotFound is returned when a user has not saved any preferences
+var ErrPreferencesNotFound = errors.New("preferences not found")
+
+// GetUserPreferences fetches the preferences of a user
+func (es *ElasticsearchDB) GetUserPreferences(userID string) (*models.UserPreferences, error) {
+	res, err := es.client.Get("user_preferences", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, ErrPreferencesNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting preferences: %s", res.String())
+	}
+
+	var result struct {
+		Source models.UserPreferences `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result.Source, nil
+}
+
+// SaveUserPreferences creates or replaces the preferences of a user
+func (es *ElasticsearchDB) SaveUserPreferences(prefs *models.UserPreferences) error {
+	prefs.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+
+	res, err := es.client.Index(
+		"user_preferences",
+		strings.NewReader(string(data)),
+		es.client.Index.WithDocumentID(prefs.UserID),
+		es.client.Index.WithRefresh("true"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error saving preferences: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// ErrQueueNotFound is returned when a social account has no posting queue
+var ErrQueueNotFound = errors.New("queue not found")
+
+// GetQueue fetches the posting queue of a social account. Deleted queues are
+// not returned.
+func (es *ElasticsearchDB) GetQueue(accountID string) (*models.PostingQueue, error) {
+	if err := es.createIndexIfNotExists("queues"); err != nil {
+		return nil, err
+	}
+
+	res, err := es.client.Get("queues", accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, ErrQueueNotFound
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("error getting queue: %s", res.String())
+	}
+
+	var result struct {
+		SeqNo       int                 `json:"_seq_no"`
+		PrimaryTerm int                 `json:"_primary_term"`
+		Source      models.PostingQueue `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if result.Source.DeletedAt != nil {
+		return nil, ErrQueueNotFound
+	}
+
+	queue := result.Source
+	queue.SeqNo = result.SeqNo
+	queue.PrimaryTerm = result.PrimaryTerm
+	return &queue, nil
+}
+
+// AccountOwnedBy reports whether a live social account belongs to the user
+func (es *ElasticsearchDB) AccountOwnedBy(accountID, userID string) (bool, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"ids": map[string]interface{}{"values": []string{accountID}}},
+					map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+				},
+				"must_not": notDeleted,
+			},
+		},
+		"size": 1,
+	}
+	return es.Exists("social_accounts", query)
+}
+
+// SaveQueue creates or replaces the posting queue of a social account. A queue
+// read from the index is only replaced if it has not changed since, and a new
+// one only created if none exists; otherwise ErrVersionConflict is returned.
+func (es *ElasticsearchDB) SaveQueue(queue *models.PostingQueue) error {
+	if queue.CreatedAt.IsZero() {
+		queue.CreatedAt = time.Now()
+	}
+	queue.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(queue)
+	if err != nil {
+		return err
+	}
+
+	opts := []func(*esapi.IndexRequest){
+		es.client.Index.WithDocumentID(queue.AccountID),
+		es.client.Index.WithRefresh("true"),
+	}
+	if queue.PrimaryTerm > 0 {
+		opts = append(opts,
+			es.client.Index.WithIfSeqNo(queue.SeqNo),
+			es.client.Index.WithIfPrimaryTerm(queue.PrimaryTerm),
+		)
+	} else {
+		opts = append(opts, es.client.Index.WithOpType("create"))
+	}
+
+	res, err := es.client.Index("queues", strings.NewReader(string(data)), opts...)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		return ErrVersionConflict
+	}
+	if res.IsError() {
+		return fmt.Errorf("error saving queue: %s", res.String())
+	}
+
+	var result struct {
+		SeqNo       int `json:"_seq_no"`
+		PrimaryTerm int `json:"_primary_term"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	queue.SeqNo = result.SeqNo
+	queue.PrimaryTerm = result.PrimaryTerm
+	return nil
+}
+
+// GetAccountScheduledTimes returns when the scheduled posts of a social
+// account, other than the excluded ones, are due after the given time
+func (es *ElasticsearchDB) GetAccountScheduledTimes(accountID string, after time.Time, excludedIDs []string) ([]time.Time, error) {
+	mustNot := []interface{}{notDeleted}
+	if len(excludedIDs) > 0 {
+		mustNot = append(mustNot, map[string]interface{}{"ids": map[string]interface{}{"values": excludedIDs}})
+	}
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"account_id": accountID}},
+					map[string]interface{}{"term": map[string]interface{}{"status": models.PostStatusScheduled}},
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"scheduled_time": map[string]interface{}{"gt": after.Format(time.RFC3339)},
+						},
+					},
+				},
+				"must_not": mustNot,
+			},
+		},
+		"_source": []string{"scheduled_time"},
+		"size":    10000,
+	}
+
+	var posts []struct {
+		ScheduledTime time.Time `json:"scheduled_time"`
+	}
+	if err := es.searchDocuments("posts", query, &posts); err != nil {
+		return nil, err
+	}
+	times := make([]time.Time, len(posts))
+	for i, post := range posts {
+		times[i] = post.ScheduledTime
+	}
+	return times, nil
+}
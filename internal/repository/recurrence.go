@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// ErrOccurrenceExists is returned when an occurrence of a series has already been materialized
+var ErrOccurrenceExists = errors.New("occurrence already exists")
+
+// GetActiveSeries returns the templates of recurring series that have not been ended
+func (es *ElasticsearchDB) GetActiveSeries(size int) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"exists": map[string]interface{}{"field": "recurrence.rrule"},
+				},
+				"must_not": []interface{}{
+					notDeleted,
+					map[string]interface{}{
+						"exists": map[string]interface{}{"field": "recurrence.ended_at"},
+					},
+				},
+			},
+		},
+		"size": size,
+	}
+
+	return es.searchPosts(query)
+}
+
+// GetSeriesOccurrences returns the materialized occurrences of a series scheduled at or after from
+func (es *ElasticsearchDB) GetSeriesOccurrences(seriesID string, from time.Time) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"series_id": seriesID}},
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"occurrence_time": map[string]interface{}{"gte": from.Format(time.RFC3339)},
+						},
+					},
+				},
+				"must_not": notDeleted,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"occurrence_time": "asc"},
+		},
+		"size": 1000,
+	}
+
+	return es.searchPosts(query)
+}
+
+// GetSeriesOccurrence returns the materialized post of a single occurrence, if any
+func (es *ElasticsearchDB) GetSeriesOccurrence(seriesID string, at time.Time) (*models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"series_id": seriesID}},
+					map[string]interface{}{"term": map[string]interface{}{"occurrence_time": at.Format(time.RFC3339)}},
+				},
+				"must_not": notDeleted,
+			},
+		},
+		"size": 1,
+	}
+
+	posts, err := es.searchPosts(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(posts) == 0 {
+		return nil, ErrPostNotFound
+	}
+	return &posts[0], nil
+}
+
+// occurrenceID derives the post ID of an occurrence from its series and time,
+// so that each occurrence maps to a single document
+func occurrenceID(seriesID string, at time.Time) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("series:"+seriesID+"/"+at.UTC().Format(time.RFC3339))).String()
+}
+
+// CreateOccurrence stores the post of a series occurrence. Its ID is derived
+// from the series and the occurrence time and the write only creates, so an
+// occurrence is materialized once even when a run is repeated; ErrOccurrenceExists
+// is returned for an occurrence that already has a live post. A trashed post
+// left by a cancelled schedule is replaced.
+func (es *ElasticsearchDB) CreateOccurrence(post *models.Post, editorID string) error {
+	if err := es.createIndexIfNotExists("posts"); err != nil {
+		return err
+	}
+
+	post.ID = occurrenceID(post.SeriesID, *post.OccurrenceTime)
+	post.CreatedAt = time.Now()
+	post.UpdatedAt = post.CreatedAt
+	post.ScheduledTime = post.ScheduledTime.UTC()
+	post.Version = 1
+
+	data, err := json.Marshal(post)
+	if err != nil {
+		return err
+	}
+	res, err := es.client.Index(
+		"posts",
+		strings.NewReader(string(data)),
+		es.client.Index.WithDocumentID(post.ID),
+		es.client.Index.WithOpType("create"),
+		es.client.Index.WithRefresh("true"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		existing, err := es.GetPost(post.ID)
+		if err != nil {
+			return err
+		}
+		if existing.DeletedAt == nil {
+			return ErrOccurrenceExists
+		}
+		post.CreatedAt = existing.CreatedAt
+		post.Version = existing.Version
+		post.SeqNo, post.PrimaryTerm = existing.SeqNo, existing.PrimaryTerm
+		return es.UpdatePost(post, editorID)
+	}
+	if res.IsError() {
+		return fmt.Errorf("error creating occurrence: %s", res.String())
+	}
+
+	var result struct {
+		SeqNo       int `json:"_seq_no"`
+		PrimaryTerm int `json:"_primary_term"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return err
+	}
+	post.SeqNo = result.SeqNo
+	post.PrimaryTerm = result.PrimaryTerm
+
+	return es.createPostRevision(nil, post, editorID)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// ErrRevisionNotFound is returned when a post revision does not exist
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// createPostRevision writes an immutable revision holding the new state of a post
+// and the fields that changed since the previous one
+func (es *ElasticsearchDB) createPostRevision(previous, post *models.Post, editorID string) error {
+	rev := newPostRevision(previous, post, editorID)
+
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+
+	// op_type=create keeps revisions immutable
+	res, err := es.client.Create(
+		"post_revisions",
+		rev.ID,
+		strings.NewReader(string(data)),
+		es.client.Create.WithRefresh("true"),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error creating post revision: %s", res.String())
+	}
+	return nil
+}
+
+func newPostRevision(previous, post *models.Post, editorID string) models.PostRevision {
+	rev := models.PostRevision{
+		ID:        uuid.New().String(),
+		PostID:    post.ID,
+		Version:   post.Version,
+		EditorID:  editorID,
+		Snapshot:  *post,
+		CreatedAt: time.Now(),
+	}
+	if previous != nil {
+		rev.Changes = models.DiffPosts(previous, post)
+	}
+	return rev
+}
+
+// GetPostRevisions returns all revisions of a post ordered by version
+func (es *ElasticsearchDB) GetPostRevisions(postID string) ([]models.PostRevision, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{
+				"post_id": postID,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"version": "asc"},
+		},
+		"size": 1000,
+	}
+
+	return es.searchPostRevisions(query)
+}
+
+// GetPostRevision returns a single revision of a post by version number
+func (es *ElasticsearchDB) GetPostRevision(postID string, version int) (*models.PostRevision, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"post_id": postID}},
+					map[string]interface{}{"term": map[string]interface{}{"version": version}},
+				},
+			},
+		},
+		"size": 1,
+	}
+
+	revisions, err := es.searchPostRevisions(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, ErrRevisionNotFound
+	}
+	return &revisions[0], nil
+}
+
+func (es *ElasticsearchDB) searchPostRevisions(query map[string]interface{}) ([]models.PostRevision, error) {
+	res, err := es.client.Search(
+		es.client.Search.WithIndex("post_revisions"),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("error searching post revisions: %s", res.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source models.PostRevision `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	revisions := make([]models.PostRevision, len(result.Hits.Hits))
+	for i, hit := range result.Hits.Hits {
+		revisions[i] = hit.Source
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// GetDuePosts returns scheduled posts whose scheduled time has passed, oldest
+// first, leaving out posts to any of the excluded platforms
+func (es *ElasticsearchDB) GetDuePosts(now time.Time, excluded []string, size int) ([]models.Post, error) {
+	mustNot := []interface{}{
+		notDeleted,
+		map[string]interface{}{"exists": map[string]interface{}{"field": "recurrence.rrule"}},
+	}
+	if len(excluded) > 0 {
+		mustNot = append(mustNot, map[string]interface{}{"terms": map[string]interface{}{"platforms": excluded}})
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"status": models.PostStatusScheduled}},
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"scheduled_time": map[string]interface{}{"lte": now.Format(time.RFC3339)},
+						},
+					},
+				},
+				"must_not": mustNot,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"scheduled_time": "asc"},
+		},
+		"size": size,
+	}
+
+	return es.searchPosts(query)
+}
+
+// GetPostsPublishingSince returns posts that have been publishing since before
+// the given time, oldest first
+func (es *ElasticsearchDB) GetPostsPublishingSince(before time.Time, size int) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"status": models.PostStatusPublishing}},
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"updated_at": map[string]interface{}{"lt": before.Format(time.RFC3339)},
+						},
+					},
+				},
+				"must_not": notDeleted,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"updated_at": "asc"},
+		},
+		"size": size,
+	}
+
+	return es.searchPosts(query)
+}
+
+// pendingCommentsQuery matches published posts that still have follow-up comments to post
+func pendingCommentsQuery(filters ...interface{}) map[string]interface{} {
+	filters = append(filters,
+		map[string]interface{}{"term": map[string]interface{}{"status": models.PostStatusPublished}},
+		map[string]interface{}{"term": map[string]interface{}{"comments.status": models.CommentStatusPending}},
+	)
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter":   filters,
+			"must_not": notDeleted,
+		},
+	}
+}
+
+// GetPostsWithDueComments returns published posts whose earliest pending
+// follow-up comment is due at now, most overdue first. Posts saved before the
+// due time was recorded are included until they are saved again.
+func (es *ElasticsearchDB) GetPostsWithDueComments(now time.Time, size int) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": pendingCommentsQuery(map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"next_comment_at": map[string]interface{}{"lte": now.Format(time.RFC3339)},
+						},
+					},
+					map[string]interface{}{
+						"bool": map[string]interface{}{
+							"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": "next_comment_at"}},
+						},
+					},
+				},
+				"minimum_should_match": 1,
+			},
+		}),
+		"sort": []interface{}{
+			map[string]interface{}{"next_comment_at": map[string]interface{}{"order": "asc", "missing": "_first"}},
+		},
+		"size": size,
+	}
+
+	return es.searchPosts(query)
+}
+
+// GetUserPostsWithPendingComments returns the published posts of a user that
+// still have pending follow-up comments
+func (es *ElasticsearchDB) GetUserPostsWithPendingComments(userID string) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": pendingCommentsQuery(
+			map[string]interface{}{"term": map[string]interface{}{"user_id": userID}},
+		),
+		"sort": []interface{}{
+			map[string]interface{}{"published_at": "asc"},
+		},
+		"size": 1000,
+	}
+
+	return es.searchPosts(query)
+}
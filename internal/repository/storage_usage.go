@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// GetStorageUsage returns the storage usage of a user. Usage of users without
+// a record yet, such as those who uploaded before usage was tracked, is
+// counted from their media library and saved.
+func (es *ElasticsearchDB) GetStorageUsage(userID string) (*models.StorageUsage, error) {
+	var usage models.StorageUsage
+	found, err := es.getDocument("storage_usage", userID, &usage)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return &usage, nil
+	}
+
+	bytes, files, err := es.countUserMedia(userID)
+	if err != nil {
+		return nil, err
+	}
+	usage = models.StorageUsage{
+		UserID:    userID,
+		Plan:      models.DefaultStoragePlan,
+		Bytes:     bytes,
+		Files:     files,
+		UpdatedAt: time.Now(),
+	}
+	created, err := es.createDocument("storage_usage", userID, &usage)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		// Counted concurrently by another request, whose record may already
+		// include later changes
+		return es.GetStorageUsage(userID)
+	}
+	return &usage, nil
+}
+
+// ListStorageUsage returns the users storing the most media first
+func (es *ElasticsearchDB) ListStorageUsage(size int) ([]models.StorageUsage, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{"match_all": map[string]interface{}{}},
+		"sort": []interface{}{
+			map[string]interface{}{"bytes": "desc"},
+		},
+		"size": size,
+	}
+
+	var usages []models.StorageUsage
+	err := es.searchDocuments("storage_usage", query, &usages)
+	return usages, err
+}
+
+// AddStorageUsage adjusts the usage of a user by the given number of bytes
+// and files, which are negative for removed media
+func (es *ElasticsearchDB) AddStorageUsage(userID string, bytes int64, files int) error {
+	if _, err := es.GetStorageUsage(userID); err != nil {
+		return err
+	}
+
+	_, err := es.updateStorageUsage(userID, `
+		ctx._source.bytes = Math.max(0, ctx._source.bytes + params.bytes);
+		ctx._source.files = Math.max(0, ctx._source.files + params.files);
+		ctx._source.updated_at = params.now;`,
+		map[string]interface{}{
+			"bytes": bytes,
+			"files": files,
+			"now":   time.Now(),
+		})
+	return err
+}
+
+// ReserveStorage adds a new file of size bytes to the usage of a user unless
+// it does not fit the quota, in which case models.ErrStorageQuotaExceeded is
+// returned. The check and the update are a single write, so concurrent
+// uploads cannot together exceed the quota.
+func (es *ElasticsearchDB) ReserveStorage(userID string, size int64) error {
+	usage, err := es.GetStorageUsage(userID)
+	if err != nil {
+		return err
+	}
+
+	limits := usage.Limits()
+	reserved, err := es.updateStorageUsage(userID, `
+		if ((params.max_files > 0 && ctx._source.files + 1 > params.max_files) ||
+				(params.max_bytes > 0 && ctx._source.bytes + params.bytes > params.max_bytes)) {
+			ctx.op = 'none';
+		} else {
+			ctx._source.bytes += params.bytes;
+			ctx._source.files += 1;
+			ctx._source.updated_at = params.now;
+		}`,
+		map[string]interface{}{
+			"bytes":     size,
+			"max_bytes": limits.MaxBytes,
+			"max_files": limits.MaxFiles,
+			"now":       time.Now(),
+		})
+	if err != nil || reserved {
+		return err
+	}
+
+	// Explain the refusal from the usage that caused it
+	usage, err = es.GetStorageUsage(userID)
+	if err != nil {
+		return err
+	}
+	if err := usage.CheckUpload(size); err != nil {
+		return err
+	}
+	return models.ErrStorageQuotaExceeded
+}
+
+// updateStorageUsage runs a script on the usage record of a user and reports
+// whether it changed the record
+func (es *ElasticsearchDB) updateStorageUsage(userID, source string, params map[string]interface{}) (bool, error) {
+	body := toJSON(map[string]interface{}{
+		"script": map[string]interface{}{
+			"source": source,
+			"lang":   "painless",
+			"params": params,
+		},
+	})
+	res, err := es.client.Update(
+		"storage_usage",
+		userID,
+		strings.NewReader(body),
+		es.client.Update.WithRetryOnConflict(5),
+		es.client.Update.WithRefresh("true"),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return false, fmt.Errorf("error updating storage usage: %s", res.String())
+	}
+
+	var result struct {
+		Result string `json:"result"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Result != "noop", nil
+}
+
+// UpdateStorageQuota changes the plan and quota override of a user
+func (es *ElasticsearchDB) UpdateStorageQuota(userID, plan string, quota *models.StorageQuota) (*models.StorageUsage, error) {
+	if _, err := es.GetStorageUsage(userID); err != nil {
+		return nil, err
+	}
+	err := es.updateDocument("storage_usage", userID, map[string]interface{}{
+		"plan":       plan,
+		"quota":      quota,
+		"updated_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return es.GetStorageUsage(userID)
+}
+
+// RecalculateStorageUsage recounts the usage of a user from the media library
+func (es *ElasticsearchDB) RecalculateStorageUsage(userID string) (*models.StorageUsage, error) {
+	if _, err := es.GetStorageUsage(userID); err != nil {
+		return nil, err
+	}
+	bytes, files, err := es.countUserMedia(userID)
+	if err != nil {
+		return nil, err
+	}
+	err = es.updateDocument("storage_usage", userID, map[string]interface{}{
+		"bytes":      bytes,
+		"files":      files,
+		"updated_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return es.GetStorageUsage(userID)
+}
+
+// countUserMedia sums the size and number of the assets of a user
+func (es *ElasticsearchDB) countUserMedia(userID string) (int64, int, error) {
+	if err := es.createIndexIfNotExists("media"); err != nil {
+		return 0, 0, err
+	}
+
+	query := map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{"user_id": userID},
+		},
+		"aggs": map[string]interface{}{
+			"bytes": map[string]interface{}{
+				"sum": map[string]interface{}{"field": "size"},
+			},
+		},
+		"track_total_hits": true,
+	}
+
+	res, err := es.client.Search(
+		es.client.Search.WithIndex("media"),
+		es.client.Search.WithBody(strings.NewReader(toJSON(query))),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, 0, fmt.Errorf("error counting media: %s", res.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+		Aggregations struct {
+			Bytes struct {
+				Value float64 `json:"value"`
+			} `json:"bytes"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return 0, 0, err
+	}
+	return int64(result.Aggregations.Bytes.Value), result.Hits.Total.Value, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a post template does not exist
+	ErrTemplateNotFound = errors.New("template not found")
+	// ErrSnippetNotFound is returned when a snippet does not exist
+	ErrSnippetNotFound = errors.New("snippet not found")
+)
+
+func (es *ElasticsearchDB) CreateTemplate(template *models.PostTemplate) error {
+	template.ID = uuid.New().String()
+	template.CreatedAt = time.Now()
+	return es.UpdateTemplate(template)
+}
+
+func (es *ElasticsearchDB) GetTemplate(templateID string) (*models.PostTemplate, error) {
+	var template models.PostTemplate
+	found, err := es.getDocument("templates", templateID, &template)
+	if err != nil {
+		return nil, err
+	}
+	if !found || template.DeletedAt != nil {
+		return nil, ErrTemplateNotFound
+	}
+	return &template, nil
+}
+
+func (es *ElasticsearchDB) GetUserTemplates(userID string) ([]models.PostTemplate, error) {
+	var templates []models.PostTemplate
+	err := es.searchDocuments("templates", userDocumentsQuery(userID, "name.keyword"), &templates)
+	return templates, err
+}
+
+func (es *ElasticsearchDB) UpdateTemplate(template *models.PostTemplate) error {
+	template.UpdatedAt = time.Now()
+	return es.saveDocument("templates", template.ID, template)
+}
+
+func (es *ElasticsearchDB) DeleteTemplate(template *models.PostTemplate) error {
+	now := time.Now()
+	template.DeletedAt = &now
+	return es.UpdateTemplate(template)
+}
+
+func (es *ElasticsearchDB) CreateSnippet(snippet *models.Snippet) error {
+	snippet.ID = uuid.New().String()
+	snippet.CreatedAt = time.Now()
+	return es.UpdateSnippet(snippet)
+}
+
+func (es *ElasticsearchDB) GetSnippet(snippetID string) (*models.Snippet, error) {
+	var snippet models.Snippet
+	found, err := es.getDocument("snippets", snippetID, &snippet)
+	if err != nil {
+		return nil, err
+	}
+	if !found || snippet.DeletedAt != nil {
+		return nil, ErrSnippetNotFound
+	}
+	return &snippet, nil
+}
+
+func (es *ElasticsearchDB) GetUserSnippets(userID string) ([]models.Snippet, error) {
+	var snippets []models.Snippet
+	err := es.searchDocuments("snippets", userDocumentsQuery(userID, "name"), &snippets)
+	return snippets, err
+}
+
+func (es *ElasticsearchDB) UpdateSnippet(snippet *models.Snippet) error {
+	snippet.UpdatedAt = time.Now()
+	return es.saveDocument("snippets", snippet.ID, snippet)
+}
+
+func (es *ElasticsearchDB) DeleteSnippet(snippet *models.Snippet) error {
+	now := time.Now()
+	snippet.DeletedAt = &now
+	return es.UpdateSnippet(snippet)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/models"
+)
+
+// GetUserTrashedPosts returns a page of the soft-deleted posts of a user, most
+// recently deleted first
+func (es *ElasticsearchDB) GetUserTrashedPosts(userID string, from, size int) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"match": map[string]interface{}{
+						"user_id": userID,
+					},
+				},
+				"filter": notDeleted,
+			},
+		},
+		"sort": []interface{}{
+			map[string]interface{}{"deleted_at": "desc"},
+		},
+		"from": from,
+		"size": size,
+	}
+
+	return es.searchPosts(query)
+}
+
+// RestorePost takes a post out of the trash
+func (es *ElasticsearchDB) RestorePost(post *models.Post, editorID string) error {
+	post.DeletedAt = nil
+	return es.UpdatePost(post, editorID)
+}
+
+// GetPostsDeletedBefore returns trashed posts deleted before the given time
+func (es *ElasticsearchDB) GetPostsDeletedBefore(cutoff time.Time, size int) ([]models.Post, error) {
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"range": map[string]interface{}{
+				"deleted_at": map[string]interface{}{
+					"lt": cutoff.Format(time.RFC3339),
+				},
+			},
+		},
+		"size": size,
+	}
+
+	return es.searchPosts(query)
+}
+
+// PurgePost permanently removes a post and its revision history
+func (es *ElasticsearchDB) PurgePost(postID string) error {
+	res, err := es.client.Delete("posts", postID, es.client.Delete.WithRefresh("true"))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 {
+		return fmt.Errorf("error purging post: %s", res.String())
+	}
+
+	query := map[string]interface{}{
+		"query": map[string]interface{}{
+			"term": map[string]interface{}{
+				"post_id": postID,
+			},
+		},
+	}
+
+	res, err = es.client.DeleteByQuery(
+		[]string{"post_revisions"},
+		strings.NewReader(toJSON(query)),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("error purging post revisions: %s", res.String())
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores objects as files below a directory that the API serves itself
+type Local struct {
+	dir     string
+	baseURL string
+
+	// Direct uploads are sent to uploadPath and authenticated with secret
+	secret     []byte
+	uploadPath string
+}
+
+// NewLocal stores objects in dir, served under baseURL. baseURL is either a
+// path such as /media/uploads or an absolute URL whose path the API serves.
+// The files cannot be served at the root or below /api, where the API routes
+// live; a base URL without a path is served under /media.
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if dir == "" {
+		dir = "uploads"
+	}
+	if baseURL == "" {
+		baseURL = "/media/uploads"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid media base URL %q: %v", baseURL, err)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		u.Path = "/media"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Path == "/api" || strings.HasPrefix(u.Path, "/api/") {
+		return nil, fmt.Errorf("media base URL %q overlaps the API routes", baseURL)
+	}
+	baseURL = u.String()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	return &Local{dir: dir, baseURL: baseURL}, nil
+}
+
+// Dir is the directory the files are stored in
+func (l *Local) Dir() string {
+	return l.dir
+}
+
+// legacyKey resolves the URL of a file saved before storage backends, a path
+// such as uploads/photo.jpg, to its key. URLs outside the directory do not resolve.
+func (l *Local) legacyKey(legacyURL string) (string, bool) {
+	u, err := url.Parse(legacyURL)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(filepath.Clean(l.dir), filepath.Clean(filepath.FromSlash(u.Path)))
+	if err != nil {
+		return "", false
+	}
+	key := filepath.ToSlash(rel)
+	return key, validKey(key) == nil
+}
+
+// ServePath is the URL path the files have to be served under, never the root
+func (l *Local) ServePath() string {
+	u, _ := url.Parse(l.baseURL)
+	return u.Path
+}
+
+// SignUploads enables direct uploads. Clients PUT files to URLs below
+// uploadPath, which the API has to route to a handler calling VerifyUpload.
+func (l *Local) SignUploads(secret []byte, uploadPath string) {
+	l.secret = secret
+	l.uploadPath = uploadPath
+}
+
+// PresignPut returns a signed URL below the upload path. The URL is relative
+// to the API and is valid for a body of exactly size bytes.
+func (l *Local) PresignPut(key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error) {
+	if l.secret == nil {
+		return nil, ErrPresignUnsupported
+	}
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(expires).Truncate(time.Second)
+	query := url.Values{}
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("signature", l.uploadSignature(key, size, expiresAt.Unix()))
+
+	headers := map[string]string{"Content-Length": strconv.FormatInt(size, 10)}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return &PresignedRequest{
+		Method:    http.MethodPut,
+		URL:       joinURL(l.uploadPath, awsURIEncode(key, false)) + "?" + query.Encode(),
+		Headers:   headers,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// VerifyUpload checks the query of a signed upload URL for key and returns
+// the size of the body it allows
+func (l *Local) VerifyUpload(key string, query url.Values) (int64, error) {
+	if l.secret == nil {
+		return 0, ErrPresignUnsupported
+	}
+	size, err := strconv.ParseInt(query.Get("size"), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid upload size")
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid upload expiry")
+	}
+
+	expected := l.uploadSignature(key, size, expires)
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(expected)) {
+		return 0, errors.New("invalid upload signature")
+	}
+	if time.Now().Unix() > expires {
+		return 0, errors.New("upload URL has expired")
+	}
+	return size, nil
+}
+
+func (l *Local) uploadSignature(key string, size int64, expires int64) string {
+	return hex.EncodeToString(hmacSHA256(l.secret, fmt.Sprintf("PUT\n%s\n%d\n%d", key, size, expires)))
+}
+
+func (l *Local) path(key string) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (l *Local) URL(key string) string {
+	return joinURL(l.baseURL, key)
+}
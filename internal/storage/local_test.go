@@ -0,0 +1,26 @@
+package storage
+
+import "testing"
+
+func TestLegacyKey(t *testing.T) {
+	l := &Local{dir: "uploads"}
+
+	tests := []struct {
+		url  string
+		key  string
+		want bool
+	}{
+		{"uploads/photo.jpg", "photo.jpg", true},
+		{"./uploads/user/photo.jpg", "user/photo.jpg", true},
+		{"https://cdn.example.com/uploads/photo.jpg", "", false},
+		{"uploads/../config.yaml", "", false},
+		{"/etc/passwd", "", false},
+	}
+
+	for _, tt := range tests {
+		key, ok := l.legacyKey(tt.url)
+		if ok != tt.want || (ok && key != tt.key) {
+			t.Errorf("legacyKey(%q) = %q, %v, want %q, %v", tt.url, key, ok, tt.key, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrOffsetMismatch is returned when a chunk does not start where the
+	// received data ends
+	ErrOffsetMismatch = errors.New("upload offset does not match")
+	// ErrUploadBusy is returned when another request is writing to the upload
+	ErrUploadBusy = errors.New("upload is being written by another request")
+	// ErrUploadTooLarge is returned when a chunk goes past the upload length
+	ErrUploadTooLarge = errors.New("chunk exceeds the upload length")
+)
+
+// staleLockAge is how old the lock of an upload has to be to be taken over.
+// Locks are removed when a request ends; an older one was left by an
+// instance that stopped while writing.
+const staleLockAge = time.Hour
+
+// Staging keeps the data of resumable uploads received so far on disk until
+// the upload is complete and moved to the storage backend. Every instance of
+// the API receiving chunks of an upload needs the same directory, e.g. a
+// shared volume; uploads are locked with lock files next to their data.
+type Staging struct {
+	dir string
+}
+
+func NewStaging(dir string) (*Staging, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Staging{dir: dir}, nil
+}
+
+func (s *Staging) path(id string) (string, error) {
+	if err := validKey(id); err != nil || strings.Contains(id, "/") {
+		return "", errors.New("invalid upload ID")
+	}
+	return filepath.Join(s.dir, id+".part"), nil
+}
+
+// Create starts an empty upload
+func (s *Staging) Create(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Offset returns the number of bytes received so far
+func (s *Staging) Offset(id string) (int64, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Append writes a chunk starting at offset, which has to be the current
+// offset, and returns the new offset. Data received before a failure is kept
+// so the client can resume from there; a chunk that goes past length is
+// rejected as a whole. The caller has to hold the lock of the upload.
+func (s *Staging) Append(id string, offset int64, r io.Reader, length int64) (int64, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() != offset {
+		return info.Size(), ErrOffsetMismatch
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	// Long chunks keep the lock fresh so it is not taken over as stale
+	r = &lockRefresher{r: r, lock: path + ".lock", touched: time.Now()}
+	n, err := io.Copy(f, io.LimitReader(r, length-offset))
+	if err != nil {
+		return offset + n, err
+	}
+	var extra [1]byte
+	if m, _ := io.ReadFull(r, extra[:]); m > 0 {
+		if err := f.Truncate(offset); err != nil {
+			return offset + n, err
+		}
+		return offset, ErrUploadTooLarge
+	}
+	return offset + n, nil
+}
+
+// Open returns the data received so far
+func (s *Staging) Open(id string) (*os.File, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Remove discards an upload. Uploads that are already gone are not an error.
+func (s *Staging) Remove(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Lock reserves an upload for one request, so chunks and the final assembly
+// of an upload never run concurrently, on this instance or any other sharing
+// the directory. It fails with ErrUploadBusy instead of waiting.
+func (s *Staging) Lock(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	lock := path + ".lock"
+
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			return f.Close()
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		info, err := os.Stat(lock)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if attempt > 0 || (err == nil && time.Since(info.ModTime()) < staleLockAge) {
+			return ErrUploadBusy
+		}
+		if err := os.Remove(lock); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+}
+
+func (s *Staging) Unlock(id string) {
+	if path, err := s.path(id); err == nil {
+		os.Remove(path + ".lock")
+	}
+}
+
+// lockRefresher updates the modification time of a lock file while data is
+// read through it
+type lockRefresher struct {
+	r       io.Reader
+	lock    string
+	touched time.Time
+}
+
+func (l *lockRefresher) Read(p []byte) (int, error) {
+	if now := time.Now(); now.Sub(l.touched) > staleLockAge/4 {
+		os.Chtimes(l.lock, now, now)
+		l.touched = now
+	}
+	return l.r.Read(p)
+}
@@ -0,0 +1,108 @@
+// Package storage keeps uploaded media in a configurable backend and builds
+// the public URLs it is served from.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/priince9381/irm_backend/internal/config"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores objects under slash separated keys
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the content of the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL the object is served from
+	URL(key string) string
+}
+
+// ErrPresignUnsupported is returned by backends that cannot issue upload URLs
+var ErrPresignUnsupported = errors.New("direct uploads are not supported by this storage backend")
+
+// Presigner is implemented by backends that clients can upload to directly,
+// without sending the file through the API
+type Presigner interface {
+	// PresignPut returns a request that uploads size bytes to key until expires
+	PresignPut(key, contentType string, size int64, expires time.Duration) (*PresignedRequest, error)
+}
+
+// PresignedRequest describes the request a client makes to upload a file
+type PresignedRequest struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// New creates the backend selected by the configuration
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageBackend {
+	case "", "local":
+		return NewLocal(cfg.UploadDir, cfg.MediaBaseURL)
+	case "s3":
+		return NewS3(S3Options{
+			Endpoint:  cfg.AWSEndpoint,
+			Region:    cfg.AWSRegion,
+			Bucket:    cfg.AWSBucket,
+			AccessKey: cfg.AWSAccessKey,
+			SecretKey: cfg.AWSSecretKey,
+			PathStyle: cfg.AWSPathStyle,
+			PublicURL: cfg.MediaBaseURL,
+		})
+	case "memory":
+		return NewMemory(cfg.MediaBaseURL), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+}
+
+// Remove deletes a stored media file. Media saved before storage backends were
+// introduced has no key; its legacy URL is the path of the file, which is only
+// removed when it lies below the directory of local storage. Remote URLs are
+// never touched. Files that are already gone are not an error.
+func Remove(ctx context.Context, s Storage, key, legacyURL string) error {
+	if key == "" {
+		local, ok := s.(*Local)
+		if !ok {
+			return nil
+		}
+		if key, ok = local.legacyKey(legacyURL); !ok {
+			return nil
+		}
+	}
+	err := s.Delete(ctx, key)
+	if errors.Is(err, ErrNotFound) || os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// validKey rejects keys that could escape the storage root
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return nil
+}
+
+// joinURL appends a key to a base URL
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
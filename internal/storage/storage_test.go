@@ -0,0 +1,24 @@
+package storage
+
+import "testing"
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"user/abc.jpg", true},
+		{"user/..abc.jpg", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"user/../other/abc.jpg", false},
+		{"user//abc.jpg", false},
+		{"user\\..\\abc.jpg", false},
+	}
+
+	for _, tt := range tests {
+		if err := validKey(tt.key); (err == nil) != tt.valid {
+			t.Errorf("validKey(%q) error = %v, want valid %v", tt.key, err, tt.valid)
+		}
+	}
+}
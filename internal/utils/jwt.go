@@ -11,13 +11,15 @@ import (
 type JWTClaims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID uuid.UUID, email string, secretKey string) (string, error) {
+func GenerateJWT(userID uuid.UUID, email, role string, secretKey string) (string, error) {
 	claims := JWTClaims{
 		UserID: userID.String(),
 		Email:  email,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
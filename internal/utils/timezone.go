@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DST disambiguation policies for local wall-clock times
+const (
+	DSTReject     = "reject"     // fail on skipped or repeated times
+	DSTEarlier    = "earlier"    // pick the earlier instant
+	DSTLater      = "later"      // pick the later instant
+	DSTCompatible = "compatible" // skipped times move forward, repeated times take the earlier instant
+)
+
+var (
+	// ErrNonexistentLocalTime is returned for wall-clock times skipped by a DST change
+	ErrNonexistentLocalTime = errors.New("local time does not exist in timezone")
+	// ErrAmbiguousLocalTime is returned for wall-clock times repeated by a DST change
+	ErrAmbiguousLocalTime = errors.New("local time is ambiguous in timezone")
+)
+
+// localTimeLayouts are the accepted formats for wall-clock times without an offset
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseLocalTime interprets a wall-clock time without offset in the given location.
+// Times that fall into a DST gap or overlap are resolved according to policy.
+func ParseLocalTime(value string, loc *time.Location, policy string) (time.Time, error) {
+	var wall time.Time
+	var err error
+	for _, layout := range localTimeLayouts {
+		if wall, err = time.Parse(layout, value); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid local time %q", value)
+	}
+
+	return ResolveLocalTime(wall, loc, policy)
+}
+
+// ResolveLocalTime maps the wall clock of wall (its location is ignored) to an
+// instant in loc, applying policy when the wall clock is skipped or repeated
+func ResolveLocalTime(wall time.Time, loc *time.Location, policy string) (time.Time, error) {
+	if policy == "" {
+		policy = DSTReject
+	}
+
+	// The wall clock read as if it were UTC; each candidate offset shifts it to an instant
+	naive := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.UTC)
+
+	// Offsets in effect around the wall clock cover both sides of any transition
+	_, before := naive.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := naive.Add(24 * time.Hour).In(loc).Zone()
+	offsets := []int{before}
+	if after != before {
+		offsets = append(offsets, after)
+	}
+
+	var candidates []time.Time
+	for _, offset := range offsets {
+		t := naive.Add(-time.Duration(offset) * time.Second).In(loc)
+		if sameWallClock(t, naive) {
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 2:
+		switch policy {
+		case DSTEarlier, DSTCompatible:
+			return candidates[0], nil
+		case DSTLater:
+			return candidates[1], nil
+		}
+		return time.Time{}, fmt.Errorf("%w: %s in %s", ErrAmbiguousLocalTime, naive.Format("2006-01-02T15:04:05"), loc)
+	}
+
+	// The wall clock was skipped: shift it by the offset on either side of the gap
+	earlier := naive.Add(-time.Duration(after) * time.Second).In(loc)
+	later := naive.Add(-time.Duration(before) * time.Second).In(loc)
+	switch policy {
+	case DSTEarlier:
+		return earlier, nil
+	case DSTLater, DSTCompatible:
+		return later, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s in %s", ErrNonexistentLocalTime, naive.Format("2006-01-02T15:04:05"), loc)
+}
+
+// ValidDSTPolicy reports whether policy is a known DST disambiguation policy
+func ValidDSTPolicy(policy string) bool {
+	switch policy {
+	case "", DSTReject, DSTEarlier, DSTLater, DSTCompatible:
+		return true
+	}
+	return false
+}
+
+func sameWallClock(t, wall time.Time) bool {
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := wall.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2 &&
+		t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second()
+}
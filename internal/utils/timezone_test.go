@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResolveLocalTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Berlin skips 02:00-03:00 on 2024-03-31 and repeats 02:00-03:00 on 2024-10-27
+	normal := time.Date(2024, 6, 1, 9, 30, 0, 0, time.UTC)
+	gap := time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC)
+	overlap := time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		wall    time.Time
+		policy  string
+		want    string
+		wantErr error
+	}{
+		{"normal time", normal, "", "2024-06-01T07:30:00Z", nil},
+		{"location of wall ignored", time.Date(2024, 6, 1, 9, 30, 0, 0, berlin), DSTReject, "2024-06-01T07:30:00Z", nil},
+		{"gap rejected by default", gap, "", "", ErrNonexistentLocalTime},
+		{"gap earlier", gap, DSTEarlier, "2024-03-31T00:30:00Z", nil},
+		{"gap later", gap, DSTLater, "2024-03-31T01:30:00Z", nil},
+		{"overlap rejected", overlap, DSTReject, "", ErrAmbiguousLocalTime},
+		{"overlap earlier", overlap, DSTEarlier, "2024-10-27T00:30:00Z", nil},
+		{"overlap compatible", overlap, DSTCompatible, "2024-10-27T00:30:00Z", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveLocalTime(tt.wall, berlin, tt.policy)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ResolveLocalTime() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveLocalTime() error = %v", err)
+			}
+			if got.UTC().Format(time.RFC3339) != tt.want {
+				t.Errorf("ResolveLocalTime() = %s, want %s", got.UTC().Format(time.RFC3339), tt.want)
+			}
+			if got.Location() != berlin {
+				t.Errorf("location = %s, want %s", got.Location(), berlin)
+			}
+		})
+	}
+}